@@ -0,0 +1,43 @@
+package errors
+
+// AssertionFailureKey is the detail key AssertionFailedf flags its errors
+// with.
+const AssertionFailureKey = "errors.assertion_failure"
+
+// AssertionFailedf returns an error reporting that an invariant of the
+// program does not hold, formatted as Errorf does, for the code paths that
+// can only be reached because of a bug. The error is tagged with
+// CodeInternal, the most severe code, and flagged with the detail
+// AssertionFailureKey, so that monitoring can tell bugs from the failures
+// expected in operation; see IsAssertionFailure. It records the stack trace
+// at the point it was called even if the api has stacks disabled.
+func AssertionFailedf(format string, args ...interface{}) error {
+	return globalErrorsApi().AssertionFailedf(format, args...)
+}
+
+// IsAssertionFailure reports whether err's chain holds an error created by
+// AssertionFailedf.
+func IsAssertionFailure(err error) bool {
+	for ; err != nil; err = unwrapOnce(err) {
+		d, ok := err.(*withDetails)
+		if !ok {
+			continue
+		}
+		for _, kv := range d.details {
+			if kv.key == AssertionFailureKey && kv.value == true {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *errorsApi) AssertionFailedf(format string, args ...interface{}) error {
+	st := e.callers(0)
+	if st == nil {
+		st = callers(e.cfg.CallerSkip, e.cfg.Depth)
+	}
+	var err error = e.fundamental(sprintf(format, args), st, &msgTemplate{format, args})
+	err = &withCode{annotation{err}, CodeInternal}
+	return e.created(&withDetails{annotation{err}, []detail{{AssertionFailureKey, true}}}, st)
+}
@@ -0,0 +1,24 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssertionFailedf(t *testing.T) {
+	err := AssertionFailedf("negative balance %d", -5)
+	assert.EqualError(t, err, "negative balance -5")
+	assert.True(t, IsAssertionFailure(err))
+	assert.True(t, IsAssertionFailure(Wrap(err, "settle")))
+	assert.Equal(t, CodeInternal, Code(Wrap(err, "settle")))
+	assert.Equal(t, true, Details(err)[AssertionFailureKey])
+	assert.False(t, IsAssertionFailure(Internalf("negative balance %d", -5)))
+	assert.False(t, IsAssertionFailure(WithDetails(io.EOF, AssertionFailureKey, "yes")))
+	assert.False(t, IsAssertionFailure(nil))
+
+	err = NewErrorsApi(WithDisableStack(true)).AssertionFailedf("unreachable")
+	assert.Regexp(t, "^unreachable\ngithub.com/pkg/errors.TestAssertionFailedf\t.+/github.com/pkg/errors/assertion_test.go:22$", fmt.Sprintf("%+v", err))
+}
@@ -108,3 +108,98 @@ func BenchmarkStackFormatting(b *testing.B) {
 	}
 	GlobalE = stackStr
 }
+
+func BenchmarkErrorf(b *testing.B) {
+	runs := []struct {
+		name   string
+		format string
+		args   []interface{}
+	}{
+		{"constant", "something failed", nil},
+		{"args", "something failed: %d", []interface{}{42}},
+	}
+	for _, r := range runs {
+		b.Run(r.name, func(b *testing.B) {
+			var err error
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				err = Errorf(r.format, r.args...)
+			}
+			b.StopTimer()
+			GlobalE = err
+		})
+	}
+}
+
+func BenchmarkDeepError(b *testing.B) {
+	for _, depth := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("depth-%d", depth), func(b *testing.B) {
+			err := New("root")
+			for i := 0; i < depth; i++ {
+				err = WithMessage(err, "layer")
+			}
+			var msg string
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				msg = err.Error()
+			}
+			b.StopTimer()
+			GlobalE = msg
+		})
+	}
+}
+
+func BenchmarkFormatWrapped(b *testing.B) {
+	err := Wrap(WithMessage(New("root"), "middle"), "outer")
+	for _, format := range []string{"%s", "%v"} {
+		b.Run(format, func(b *testing.B) {
+			var msg string
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				msg = fmt.Sprintf(format, err)
+			}
+			b.StopTimer()
+			GlobalE = msg
+		})
+	}
+}
+
+func BenchmarkDeepStackFormatting(b *testing.B) {
+	api := NewErrorsApi(ApiConfig{CallerSkip: 1, Depth: 32})
+	var deepErrors func(at, depth int) error
+	deepErrors = func(at, depth int) error {
+		if at >= depth {
+			return api.New("deep error")
+		}
+		return deepErrors(at+1, depth)
+	}
+	err := deepErrors(0, 32)
+	for _, format := range []string{"%v", "%+v"} {
+		b.Run(format, func(b *testing.B) {
+			var msg string
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				msg = fmt.Sprintf(format, err)
+			}
+			b.StopTimer()
+			GlobalE = msg
+		})
+	}
+}
+
+func BenchmarkMessages(b *testing.B) {
+	err := WithMessage(Wrap(Wrap(New("foo"), "bar"), "baz"), "qux")
+	b.Run("Lines", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Lines(err, false)
+		}
+	})
+	b.Run("Messages", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Messages(err)
+		}
+	})
+}
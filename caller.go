@@ -0,0 +1,70 @@
+package errors
+
+// NewWithCaller is like New, but records only the frame of its caller,
+// whatever the depth of the stacks the api captures otherwise.
+func NewWithCaller(message string) error {
+	return globalErrorsApi().NewWithCaller(message)
+}
+
+// WithCaller annotates err with the frame of its caller, like a WithStack
+// limited to a single frame.
+// If err is nil, WithCaller returns nil.
+func WithCaller(err error) error {
+	return globalErrorsApi().WithCaller(err)
+}
+
+func (e *errorsApi) NewWithCaller(message string) error {
+	st := e.caller(0)
+	return e.created(e.fundamental(message, st, nil), st)
+}
+
+func (e *errorsApi) WithCaller(err error) error {
+	if err == nil {
+		return nil
+	}
+	if c, ok := e.collapse(err); ok {
+		return c
+	}
+	return e.withStack(err, "", nil, e.caller(0))
+}
+
+// caller is like callers, but captures a single frame.
+func (e *errorsApi) caller(skip int) *stack {
+	disable := e.cfg.DisableStack
+	if e.rules != nil {
+		if rule := e.rules.lookup(e.cfg.CallerSkip + skip + 1); rule != nil {
+			disable = rule.DisableStack
+		}
+	}
+	if disable {
+		return nil
+	}
+	if e.cfg.FrameFilter != nil {
+		return filteredCallers(e.cfg.CallerSkip+skip+1, 1, e.cfg.FrameFilter)
+	}
+	return callers(e.cfg.CallerSkip+skip+1, 1)
+}
+
+// Caller returns the frame of the function skip levels above the caller of
+// Caller: 0 is the caller of Caller itself, as for runtime.Caller. The
+// frame is resolved as the frames of captured stacks are, so log prefixes
+// built from it match them. If there is no such frame, Caller returns the
+// zero Frame, which formats as unknown.
+func Caller(skip int) Frame {
+	st := callers(skip+1, 1)
+	if len(*st) == 0 {
+		return 0
+	}
+	return Frame((*st)[0])
+}
+
+// FuncName returns the name of the function skip levels above the caller of
+// FuncName, qualified by its package name but not its path, as in
+// "errors.Caller", which is how WrapFn names functions.
+func FuncName(skip int) string {
+	st := callers(skip+1, 1)
+	if len(*st) == 0 {
+		return "unknown"
+	}
+	return pkgFuncname(Frame((*st)[0]).name())
+}
@@ -0,0 +1,41 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithCaller(t *testing.T) {
+	assert.Nil(t, WithCaller(nil))
+
+	err := WithCaller(io.EOF)
+	assert.Regexp(t, "^EOF\ngithub.com/pkg/errors.TestWithCaller\t.+/github.com/pkg/errors/caller_test.go:14$", fmt.Sprintf("%+v", err))
+
+	err = NewWithCaller("foo")
+	assert.Regexp(t, "^foo\ngithub.com/pkg/errors.TestWithCaller\t.+/github.com/pkg/errors/caller_test.go:17$", fmt.Sprintf("%+v", err))
+
+	api := NewErrorsApi(WithMaxDepth(8))
+	assert.Greater(t, len(api.New("deep").(*fundamental).StackTrace()), 1)
+	assert.Len(t, api.NewWithCaller("shallow").(*fundamental).StackTrace(), 1)
+	assert.Len(t, api.WithCaller(io.EOF).(*withStack).StackTrace(), 1)
+}
+
+func callerHelper() (Frame, string) {
+	return Caller(1), FuncName(1)
+}
+
+func TestCallerAndFuncName(t *testing.T) {
+	f := Caller(0)
+	assert.Regexp(t, "^github.com/pkg/errors.TestCallerAndFuncName\t.+/github.com/pkg/errors/caller_test.go:31$", fmt.Sprintf("%+v", f))
+	assert.Equal(t, "errors.TestCallerAndFuncName", FuncName(0))
+
+	f, name := callerHelper()
+	assert.Regexp(t, "^github.com/pkg/errors.TestCallerAndFuncName\t.+/github.com/pkg/errors/caller_test.go:35$", fmt.Sprintf("%+v", f))
+	assert.Equal(t, "errors.TestCallerAndFuncName", name)
+
+	assert.Equal(t, Frame(0), Caller(1000))
+	assert.Equal(t, "unknown", FuncName(1000))
+}
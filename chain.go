@@ -0,0 +1,138 @@
+package errors
+
+// unwrapOnce returns the next error in err's chain, following Unwrap and
+// falling back to Cause for errors that only implement the latter.
+func unwrapOnce(err error) error {
+	switch err := err.(type) {
+	case interface{ Unwrap() error }:
+		return err.Unwrap()
+	case interface{ Cause() error }:
+		return err.Cause()
+	}
+	return nil
+}
+
+// Chain returns every error in err's chain, starting with err itself and
+// following Unwrap, or Cause for errors that do not implement Unwrap.
+// If err is nil, Chain returns nil.
+func Chain(err error) []error {
+	var chain []error
+	for ; err != nil; err = unwrapOnce(err) {
+		chain = append(chain, err)
+	}
+	return chain
+}
+
+// Depth returns the number of errors in err's chain, counting err itself,
+// as followed by Chain. It is 0 for nil and 1 for an error that wraps
+// nothing. Errors that join several others end the chain.
+func Depth(err error) int {
+	n := 0
+	for ; err != nil; err = unwrapOnce(err) {
+		n++
+	}
+	return n
+}
+
+// RootCause returns the innermost error in err's chain. Unlike Cause, which
+// stops at the first error that does not implement Cause, RootCause also
+// follows Unwrap, so errors wrapped by fmt.Errorf with %w do not hide the
+// root of the chain.
+// If err is nil, RootCause returns nil.
+func RootCause(err error) error {
+	if err == nil {
+		return nil
+	}
+	for next := unwrapOnce(err); next != nil; next = unwrapOnce(err) {
+		err = next
+	}
+	return err
+}
+
+// ReplaceCause returns a copy of err's chain with the same wrappers, such
+// as messages, stacks, codes and details, around newCause instead of err's
+// cause. The chain is copied down to the first error that is not a wrapper
+// of this package; that error and everything beneath it is replaced, so
+// internal root causes can be swapped for sanitized ones before errors are
+// returned to clients. If err has no wrappers, ReplaceCause returns
+// newCause; if newCause is nil, it returns nil.
+func ReplaceCause(err, newCause error) error {
+	if newCause == nil {
+		return nil
+	}
+	r, ok := err.(rewrapper)
+	if !ok {
+		return newCause
+	}
+	cause := unwrapOnce(err)
+	if cause == nil {
+		return newCause
+	}
+	return r.rewrap(ReplaceCause(cause, newCause))
+}
+
+// RootMessage returns the message of the innermost error in err's chain,
+// without the messages of the layers wrapping it.
+// If err is nil, RootMessage returns an empty string.
+func RootMessage(err error) string {
+	err = RootCause(err)
+	if err == nil {
+		return ""
+	}
+	if l, ok := err.(layer); ok {
+		return l.layerMessage()
+	}
+	return err.Error()
+}
+
+// OutermostMessage returns the message of the outermost layer of err that
+// has one, such as the message of the latest Wrap, without the messages of
+// its causes. Errors from other packages do not separate their own message
+// from their cause's, so for them the full Error() text is returned.
+// If err is nil, OutermostMessage returns an empty string.
+func OutermostMessage(err error) string {
+	for err != nil {
+		switch e := err.(type) {
+		case layer:
+			if msg := e.layerMessage(); msg != "" {
+				return msg
+			}
+		case Liner:
+			if msg := e.ErrorLineV(VerbosityNormal); msg != "" {
+				return msg
+			}
+		default:
+			return err.Error()
+		}
+		err = unwrapOnce(err)
+	}
+	return ""
+}
+
+// Walk calls fn for err and every error reachable from it, in depth-first
+// order. Linear chains are followed through Unwrap or Cause, and errors
+// that implement Unwrap() []error, such as those returned by Join, have
+// each of their children walked in turn. depth is 0 for err itself and
+// grows by one for each level of wrapping. If fn returns false, Walk stops
+// without visiting any further errors.
+func Walk(err error, fn func(err error, depth int) bool) {
+	walk(err, 0, fn)
+}
+
+func walk(err error, depth int, fn func(error, int) bool) bool {
+	for ; err != nil; err = unwrapOnce(err) {
+		if !fn(err, depth) {
+			return false
+		}
+		if multi, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, child := range multi.Unwrap() {
+				if !walk(child, depth+1, fn) {
+					return false
+				}
+			}
+			return true
+		}
+		depth++
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRootMessage(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{io.EOF, "EOF"},
+		{New("foo"), "foo"},
+		{Wrap(New("foo"), "bar"), "foo"},
+		{WithMessage(WithStack(Wrap(io.EOF, "foo")), "bar"), "EOF"},
+		{Wrap(fmt.Errorf("foo: %w", New("bar")), "baz"), "bar"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, RootMessage(tt.err), "RootMessage(%v)", tt.err)
+	}
+}
+
+func TestOutermostMessage(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{io.EOF, "EOF"},
+		{New("foo"), "foo"},
+		{Wrap(New("foo"), "bar"), "bar"},
+		{WithStack(WithMessage(io.EOF, "foo")), "foo"},
+		{WithStack(io.EOF), "EOF"},
+		{WithStack(fmt.Errorf("foo: %w", New("bar"))), "foo: bar"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, OutermostMessage(tt.err), "OutermostMessage(%v)", tt.err)
+	}
+}
+
+type causeOnly struct{ cause error }
+
+func (c causeOnly) Error() string { return "cause only: " + c.cause.Error() }
+func (c causeOnly) Cause() error  { return c.cause }
+
+func TestChain(t *testing.T) {
+	root := New("root")
+	withMsg := WithMessage(root, "msg")
+	legacy := causeOnly{withMsg}
+	wrapped := Wrap(legacy, "wrap")
+
+	assert.Nil(t, Chain(nil))
+	assert.Equal(t, []error{io.EOF}, Chain(io.EOF))
+	assert.Equal(t, []error{wrapped, legacy, withMsg, root}, Chain(wrapped))
+}
+
+type multiError []error
+
+func (m multiError) Error() string   { return fmt.Sprint([]error(m)) }
+func (m multiError) Unwrap() []error { return m }
+
+func TestWalk(t *testing.T) {
+	a := New("a")
+	b := WithMessage(io.EOF, "b")
+	multi := multiError{a, b}
+	err := Wrap(multi, "top")
+
+	type visit struct {
+		err   error
+		depth int
+	}
+	var got []visit
+	Walk(err, func(err error, depth int) bool {
+		got = append(got, visit{err, depth})
+		return true
+	})
+	assert.Equal(t, []visit{{err, 0}, {multi, 1}, {a, 2}, {b, 2}, {io.EOF, 3}}, got)
+
+	got = nil
+	Walk(err, func(err error, depth int) bool {
+		got = append(got, visit{err, depth})
+		return err != a
+	})
+	assert.Equal(t, []visit{{err, 0}, {multi, 1}, {a, 2}}, got)
+
+	Walk(nil, func(error, int) bool {
+		t.Fatal("fn called for nil error")
+		return true
+	})
+}
+
+func TestRootCause(t *testing.T) {
+	mixed := Wrap(fmt.Errorf("std: %w", WithMessage(io.EOF, "inner")), "outer")
+	assert.Nil(t, RootCause(nil))
+	assert.Same(t, io.EOF, RootCause(io.EOF))
+	assert.Same(t, io.EOF, RootCause(Wrap(io.EOF, "foo")))
+	assert.Same(t, io.EOF, RootCause(mixed))
+	assert.NotSame(t, io.EOF, Cause(mixed))
+}
+
+func TestDepth(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{io.EOF, 1},
+		{New("foo"), 1},
+		{Wrap(io.EOF, "foo"), 2},
+		{WithMessage(WithCode(Wrap(io.EOF, "foo"), CodeInternal), "bar"), 4},
+		{fmt.Errorf("std: %w", causeOnly{io.EOF}), 3},
+		{Wrap(Join(io.EOF, io.ErrUnexpectedEOF), "foo"), 2},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Depth(tt.err), "Depth(%v)", tt.err)
+	}
+}
+
+func TestReplaceCause(t *testing.T) {
+	sanitized := New("internal error")
+	assert.Nil(t, ReplaceCause(Wrap(io.EOF, "foo"), nil))
+	assert.Same(t, sanitized, ReplaceCause(nil, sanitized))
+	assert.Same(t, sanitized, ReplaceCause(io.EOF, sanitized))
+	assert.Same(t, sanitized, ReplaceCause(New("secret"), sanitized))
+
+	err := WithMessage(WithCode(Wrap(New("secret"), "query"), CodeInternal), "load user")
+	got := ReplaceCause(err, sanitized)
+	assert.EqualError(t, got, "load user: query: internal error")
+	assert.Equal(t, CodeInternal, Code(got))
+	assert.Same(t, sanitized, RootCause(got))
+	assert.Equal(t, nearestStack(err), nearestStack(got))
+	assert.EqualError(t, err, "load user: query: secret")
+
+	mixed := Wrap(fmt.Errorf("std: %w", io.EOF), "outer")
+	assert.EqualError(t, ReplaceCause(mixed, sanitized), "outer: internal error")
+}
@@ -0,0 +1,85 @@
+package errors
+
+// checked is the panic value Check uses to hand an error to Handle.
+type checked struct {
+	err error
+}
+
+// Check panics if err is not nil, handing err, annotated with a stack trace
+// at the point Check was called, to the Handle deferred by the enclosing
+// function. Check must only be called from functions that defer Handle.
+//
+// Check and Handle are experimental.
+func Check(err error) {
+	globalErrorsApi().Check(err)
+}
+
+// Handle recovers the error passed to Check, replaces it with the result of
+// handler, if handler is not nil, and stores it in *errp. Handle must be
+// deferred directly, so that it can recover:
+//
+//	func load(path string) (cfg *Config, err error) {
+//	        defer errors.Handle(&err, func(err error) error {
+//	                return errors.WithMessage(err, "loading config")
+//	        })
+//	        data, err := os.ReadFile(path)
+//	        errors.Check(err)
+//	        errors.Check(json.Unmarshal(data, &cfg))
+//	        return cfg, nil
+//	}
+//
+// Panics that were not raised by Check are propagated unchanged.
+func Handle(errp *error, handler func(error) error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	c, ok := r.(checked)
+	if !ok {
+		panic(r)
+	}
+	err := c.err
+	if handler != nil {
+		err = handler(err)
+	}
+	*errp = err
+}
+
+func (e *errorsApi) Check(err error) {
+	if err == nil {
+		return
+	}
+	if c, ok := e.collapse(err); ok {
+		panic(checked{c})
+	}
+	panic(checked{e.withStack(err, "", nil, e.callers(0))})
+}
+
+// PanicIf panics with err, annotated with a stack trace at the point PanicIf
+// was called and the supplied message, if err is not nil. It is intended for
+// invariant violations and initialization code that cannot return an error.
+func PanicIf(err error, message string) {
+	globalErrorsApi().PanicIf(err, message)
+}
+
+// MustNil panics with err, annotated with a stack trace at the point MustNil
+// was called, if err is not nil.
+func MustNil(err error) {
+	globalErrorsApi().MustNil(err)
+}
+
+func (e *errorsApi) PanicIf(err error, message string) {
+	if err != nil {
+		panic(e.wrap(1, err, message))
+	}
+}
+
+func (e *errorsApi) MustNil(err error) {
+	if err == nil {
+		return
+	}
+	if c, ok := e.collapse(err); ok {
+		panic(c)
+	}
+	panic(e.withStack(err, "", nil, e.callers(0)))
+}
@@ -0,0 +1,83 @@
+package errors
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func checkSteps(first, second error) (n int, err error) {
+	defer Handle(&err, func(err error) error {
+		return WithMessage(err, "steps")
+	})
+	Check(first)
+	n++
+	Check(second)
+	n++
+	return n, nil
+}
+
+func TestCheckHandle(t *testing.T) {
+	n, err := checkSteps(nil, nil)
+	assert.Equal(t, 2, n)
+	assert.NoError(t, err)
+
+	_, err = checkSteps(nil, io.EOF)
+	assert.EqualError(t, err, "steps: EOF")
+	assert.Same(t, io.EOF, Cause(err))
+	matchLines(t, []string{
+		"steps",
+		"^github.com/pkg/errors.checkSteps\t.+/github.com/pkg/errors/check_test.go:16$",
+		"EOF",
+	}, Lines(err, true))
+}
+
+func TestHandleWithoutHandler(t *testing.T) {
+	err := func() (err error) {
+		defer Handle(&err, nil)
+		Check(io.EOF)
+		return nil
+	}()
+	assert.EqualError(t, err, "EOF")
+}
+
+func TestHandleRepanics(t *testing.T) {
+	assert.PanicsWithValue(t, "boom", func() {
+		var err error
+		defer Handle(&err, nil)
+		panic("boom")
+	})
+}
+
+func recoverError(f func()) (err error) {
+	defer func() {
+		err, _ = recover().(error)
+	}()
+	f()
+	return nil
+}
+
+func TestPanicIf(t *testing.T) {
+	assert.NotPanics(t, func() { PanicIf(nil, "init") })
+
+	err := recoverError(func() { PanicIf(io.EOF, "init") })
+	assert.EqualError(t, err, "init: EOF")
+	assert.Same(t, io.EOF, Cause(err))
+	matchLines(t, []string{
+		"^init\ngithub.com/pkg/errors.TestPanicIf.func2\t.+/github.com/pkg/errors/check_test.go:\\d+$",
+		"EOF",
+	}, Lines(err, true))
+}
+
+func TestMustNil(t *testing.T) {
+	assert.NotPanics(t, func() { MustNil(nil) })
+
+	err := recoverError(func() { MustNil(io.EOF) })
+	assert.EqualError(t, err, "EOF")
+	assert.Same(t, io.EOF, Cause(err))
+	matchLines(t, []string{
+		"^github.com/pkg/errors.TestMustNil.func2\t.+/github.com/pkg/errors/check_test.go:\\d+$",
+		"EOF",
+	}, Lines(err, true))
+}
@@ -0,0 +1,33 @@
+package errors
+
+import (
+	"context"
+	"io/fs"
+	"os"
+)
+
+// classify derives a code from the well-known errors of the standard
+// library found in err's chain, for errors that were not given one with
+// WithCode. It returns CodeUnknown if it finds none.
+func classify(err error) ErrorCode {
+	switch {
+	case Is(err, context.Canceled):
+		return CodeCanceled
+	case Is(err, context.DeadlineExceeded), Is(err, os.ErrDeadlineExceeded):
+		return CodeDeadlineExceeded
+	case Is(err, fs.ErrNotExist):
+		return CodeNotFound
+	case Is(err, fs.ErrExist):
+		return CodeAlreadyExists
+	case Is(err, fs.ErrPermission):
+		return CodePermissionDenied
+	}
+	if code, ok := classifyErrno(err); ok {
+		return code
+	}
+	var t interface{ Timeout() bool }
+	if As(err, &t) && t.Timeout() {
+		return CodeDeadlineExceeded
+	}
+	return CodeUnknown
+}
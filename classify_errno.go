@@ -0,0 +1,28 @@
+//go:build !plan9
+// +build !plan9
+
+package errors
+
+import "syscall"
+
+// classifyErrno derives a code from the syscall.Errno in err's chain.
+func classifyErrno(err error) (ErrorCode, bool) {
+	var errno syscall.Errno
+	if !As(err, &errno) {
+		return CodeUnknown, false
+	}
+	switch errno {
+	case syscall.ETIMEDOUT:
+		return CodeDeadlineExceeded, true
+	case syscall.ECONNREFUSED, syscall.ECONNRESET, syscall.ECONNABORTED,
+		syscall.EHOSTUNREACH, syscall.ENETUNREACH, syscall.ENETDOWN, syscall.EPIPE:
+		return CodeUnavailable, true
+	case syscall.ENOSPC, syscall.EMFILE, syscall.ENFILE, syscall.ENOMEM:
+		return CodeResourceExhausted, true
+	case syscall.EINVAL:
+		return CodeInvalidArgument, true
+	case syscall.ENOSYS:
+		return CodeUnimplemented, true
+	}
+	return CodeUnknown, false
+}
@@ -0,0 +1,7 @@
+package errors
+
+// classifyErrno derives no codes on Plan 9, whose system calls return
+// plain strings.
+func classifyErrno(err error) (ErrorCode, bool) {
+	return CodeUnknown, false
+}
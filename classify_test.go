@@ -0,0 +1,40 @@
+//go:build !plan9
+// +build !plan9
+
+package errors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeClassify(t *testing.T) {
+	_, notExist := os.Open("testdata/does-not-exist")
+	tests := []struct {
+		err  error
+		want ErrorCode
+	}{
+		{io.EOF, CodeUnknown},
+		{Wrap(context.Canceled, "query"), CodeCanceled},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), CodeDeadlineExceeded},
+		{Wrap(notExist, "load config"), CodeNotFound},
+		{&os.PathError{Op: "open", Path: "/etc/shadow", Err: syscall.EACCES}, CodePermissionDenied},
+		{&os.LinkError{Op: "link", Err: syscall.EEXIST}, CodeAlreadyExists},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, CodeUnavailable},
+		{Wrap(syscall.ETIMEDOUT, "read"), CodeDeadlineExceeded},
+		{&net.DNSError{Err: "timeout", IsTimeout: true}, CodeDeadlineExceeded},
+		{WithCode(notExist, CodeInternal), CodeInternal},
+		{Join(io.EOF, syscall.ENOSPC), CodeResourceExhausted},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Code(tt.err), "Code(%v)", tt.err)
+	}
+}
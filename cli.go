@@ -0,0 +1,178 @@
+package errors
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type cliConfig struct {
+	width   int
+	verbose bool
+	color   bool
+}
+
+// CLIOption configures a single call to FprintCLI.
+type CLIOption func(*cliConfig)
+
+// CLIWidth sets the width messages are wrapped at. The default is the
+// COLUMNS environment variable, or 80 if it is not set.
+func CLIWidth(width int) CLIOption {
+	return func(c *cliConfig) {
+		c.width = width
+	}
+}
+
+// CLIVerbose makes FprintCLI print the stack traces of the error, which
+// are otherwise only counted.
+func CLIVerbose(verbose bool) CLIOption {
+	return func(c *cliConfig) {
+		c.verbose = verbose
+	}
+}
+
+// CLIColor makes FprintCLI highlight the error and its hints with ANSI
+// escape sequences, for terminals that support them.
+func CLIColor(color bool) CLIOption {
+	return func(c *cliConfig) {
+		c.color = color
+	}
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiDim    = "\x1b[2m"
+)
+
+// FprintCLI writes err to w for the user of a command line tool to read.
+// The first line is the user message of err, or its outermost message,
+// and the layers of its chain follow, one per line, indented by their
+// depth, with messages wrapped to the width of the terminal. Hints are
+// printed last. Stack traces are left out, and only the number of their
+// frames is mentioned, unless CLIVerbose is set:
+//
+//	Error: could not load your settings
+//	  load config
+//	    open settings.yaml: no such file or directory
+//	Hint: run "tool init" to create it
+//	(4 stack frames hidden)
+//
+// If err is nil, FprintCLI writes nothing.
+func FprintCLI(w io.Writer, err error, opts ...CLIOption) error {
+	if err == nil {
+		return nil
+	}
+	c := cliConfig{width: 80}
+	if n, _ := strconv.Atoi(os.Getenv("COLUMNS")); n > 0 {
+		c.width = n
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	var b strings.Builder
+	p := cliPrinter{cliConfig: c, b: &b}
+
+	root := Tree(err)
+	headline := UserMessage(err)
+	var skipped []*Node
+	if headline == "" {
+		// The outermost message is the headline, and is not repeated.
+		for root.Message == "" && len(root.Children) == 1 {
+			skipped = append(skipped, root)
+			root = root.Children[0]
+		}
+		headline = root.Message
+		if headline == "" {
+			headline = strconv.Itoa(len(root.Children)) + " errors occurred"
+		}
+		skipped = append(skipped, root)
+		root = &Node{Children: root.Children}
+	}
+	p.wrap(p.highlight(ansiRed, "Error:")+" ", len("Error: "), headline)
+	for _, n := range skipped {
+		p.node(&Node{Stack: n.Stack}, 0, false)
+	}
+	p.node(root, 1, true)
+	for _, hint := range Hints(err) {
+		p.wrap(p.highlight(ansiYellow, "Hint:")+" ", len("Hint: "), hint)
+	}
+	if p.hidden > 0 {
+		frames := " stack frames hidden)"
+		if p.hidden == 1 {
+			frames = " stack frame hidden)"
+		}
+		p.b.WriteString(p.highlight(ansiDim, "("+strconv.Itoa(p.hidden)+frames))
+		p.b.WriteByte('\n')
+	}
+	_, werr := io.WriteString(w, b.String())
+	return werr
+}
+
+type cliPrinter struct {
+	cliConfig
+	b      *strings.Builder
+	hidden int
+}
+
+// node prints n at depth, followed by its stack and, if children is set,
+// by its children.
+func (p *cliPrinter) node(n *Node, depth int, children bool) {
+	indent := strings.Repeat("  ", depth)
+	if n.Message != "" {
+		p.wrap(indent, len(indent), n.Message)
+		depth++
+	}
+	if p.verbose {
+		for _, f := range n.Stack {
+			p.b.WriteString(indent + "    " + p.highlight(ansiDim, "at "+f.name()+" ("+f.file()+":"+strconv.Itoa(f.line())+")"))
+			p.b.WriteByte('\n')
+		}
+	} else {
+		p.hidden += len(n.Stack)
+	}
+	if !children {
+		return
+	}
+	for _, child := range n.Children {
+		p.node(child, depth, true)
+	}
+}
+
+// wrap writes prefix, which is n columns wide, and text, wrapped at the
+// width of p. Continuation lines are indented by n columns.
+func (p *cliPrinter) wrap(prefix string, n int, text string) {
+	width := p.width - n
+	if width < 20 {
+		width = 20
+	}
+	p.b.WriteString(prefix)
+	col := 0
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			p.b.WriteString("\n" + strings.Repeat(" ", n))
+			col = 0
+		}
+		for _, word := range strings.Fields(line) {
+			if col > 0 && col+1+len(word) > width {
+				p.b.WriteString("\n" + strings.Repeat(" ", n))
+				col = 0
+			} else if col > 0 {
+				p.b.WriteByte(' ')
+				col++
+			}
+			p.b.WriteString(word)
+			col += len(word)
+		}
+	}
+	p.b.WriteByte('\n')
+}
+
+func (p *cliPrinter) highlight(color, s string) string {
+	if !p.color || s == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
@@ -0,0 +1,46 @@
+package errors
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFprintCLI(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, FprintCLI(&buf, nil))
+	assert.Empty(t, buf.String())
+
+	cause := &os.PathError{Op: "open", Path: "settings.yaml", Err: io.ErrUnexpectedEOF}
+	err := Wrap(WithMessage(cause, "read settings file, which has a long message that is wrapped"), "load config")
+	assert.NoError(t, FprintCLI(&buf, err, CLIWidth(40)))
+	assert.Equal(t, `Error: load config
+  read settings file, which has a long
+  message that is wrapped
+    open settings.yaml: unexpected EOF
+      unexpected EOF
+(1 stack frame hidden)
+`, buf.String())
+
+	buf.Reset()
+	err = WithHint(WithUserMessage(err, "could not load your settings"), `run "tool init"`)
+	assert.NoError(t, FprintCLI(&buf, err, CLIWidth(40), CLIColor(true)))
+	assert.Equal(t, "\x1b[1;31mError:\x1b[0m could not load your settings\n"+
+		"  load config\n"+
+		"    read settings file, which has a long\n"+
+		"    message that is wrapped\n"+
+		"      open settings.yaml: unexpected EOF\n"+
+		"        unexpected EOF\n"+
+		"\x1b[1;33mHint:\x1b[0m run \"tool init\"\n"+
+		"\x1b[2m(1 stack frame hidden)\x1b[0m\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, FprintCLI(&buf, WithStack(Join(io.EOF, NewPlain("closed"))), CLIVerbose(true)))
+	assert.Regexp(t, "^Error: 2 errors occurred\n"+
+		"    at github.com/pkg/errors.TestFprintCLI \\(.+/github.com/pkg/errors/cli_test.go:\\d+\\)\n"+
+		"  EOF\n"+
+		"  closed\n$", buf.String())
+}
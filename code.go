@@ -0,0 +1,230 @@
+package errors
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// ErrorCode classifies an error. The codes and their meaning follow the
+// canonical codes used by gRPC.
+type ErrorCode uint32
+
+const (
+	CodeOK ErrorCode = iota
+	CodeCanceled
+	CodeUnknown
+	CodeInvalidArgument
+	CodeDeadlineExceeded
+	CodeNotFound
+	CodeAlreadyExists
+	CodePermissionDenied
+	CodeResourceExhausted
+	CodeFailedPrecondition
+	CodeAborted
+	CodeOutOfRange
+	CodeUnimplemented
+	CodeInternal
+	CodeUnavailable
+	CodeDataLoss
+	CodeUnauthenticated
+)
+
+var codeNames = [...]string{
+	CodeOK:                 "OK",
+	CodeCanceled:           "Canceled",
+	CodeUnknown:            "Unknown",
+	CodeInvalidArgument:    "InvalidArgument",
+	CodeDeadlineExceeded:   "DeadlineExceeded",
+	CodeNotFound:           "NotFound",
+	CodeAlreadyExists:      "AlreadyExists",
+	CodePermissionDenied:   "PermissionDenied",
+	CodeResourceExhausted:  "ResourceExhausted",
+	CodeFailedPrecondition: "FailedPrecondition",
+	CodeAborted:            "Aborted",
+	CodeOutOfRange:         "OutOfRange",
+	CodeUnimplemented:      "Unimplemented",
+	CodeInternal:           "Internal",
+	CodeUnavailable:        "Unavailable",
+	CodeDataLoss:           "DataLoss",
+	CodeUnauthenticated:    "Unauthenticated",
+}
+
+func (c ErrorCode) String() string {
+	if int(c) < len(codeNames) {
+		return codeNames[c]
+	}
+	return "Code(" + strconv.FormatUint(uint64(c), 10) + ")"
+}
+
+// WithCode annotates err with code.
+// If err is nil, WithCode returns nil.
+func WithCode(err error, code ErrorCode) error {
+	return globalErrorsApi().WithCode(err, code)
+}
+
+// Code returns the code of err, chosen among the codes of its chain by the
+// policy set with SetCodePolicy: by default, the code of the outermost
+// error that has one. It returns CodeOK if err is nil.
+//
+// If no error in the chain has a code, Code derives one from the errors of
+// the standard library the chain holds: CodeNotFound for fs.ErrNotExist,
+// CodePermissionDenied for fs.ErrPermission, CodeDeadlineExceeded for
+// timeouts, CodeUnavailable for refused or reset connections, and so on. It
+// returns CodeUnknown if it finds none of them either.
+func Code(err error) ErrorCode {
+	if err == nil {
+		return CodeOK
+	}
+	found := false
+	var code ErrorCode
+	policy := CodePolicy(atomic.LoadInt32(&codePolicy))
+	for e := err; e != nil; e = unwrapOnce(e) {
+		c, ok := e.(*withCode)
+		if !ok {
+			continue
+		}
+		switch {
+		case policy == CodeOutermost:
+			return c.code
+		case !found, policy == CodeInnermost,
+			policy == CodeMostSevere && CodeSeverity(c.code) > CodeSeverity(code):
+			code = c.code
+		}
+		found = true
+	}
+	if !found {
+		return classify(err)
+	}
+	return code
+}
+
+// AllCodes returns the codes attached to err's chain, outermost first.
+func AllCodes(err error) []ErrorCode {
+	var codes []ErrorCode
+	for ; err != nil; err = unwrapOnce(err) {
+		if c, ok := err.(*withCode); ok {
+			codes = append(codes, c.code)
+		}
+	}
+	return codes
+}
+
+// CodePolicy selects which of the codes of an error's chain Code returns.
+type CodePolicy int32
+
+const (
+	// CodeOutermost selects the code attached last, closest to the
+	// outermost error. It is the default.
+	CodeOutermost CodePolicy = iota
+	// CodeInnermost selects the code attached first, closest to the root
+	// cause.
+	CodeInnermost
+	// CodeMostSevere selects the code with the highest CodeSeverity; the
+	// outermost one if several share it.
+	CodeMostSevere
+)
+
+var codePolicy int32
+
+// SetCodePolicy sets the policy Code chooses codes by.
+func SetCodePolicy(policy CodePolicy) {
+	atomic.StoreInt32(&codePolicy, int32(policy))
+}
+
+// CodeSeverity ranks codes by how serious the failures they describe are,
+// from 0 for CodeOK, through 1 for failures caused by the caller, such as
+// CodeNotFound, and 2 for transient failures, such as CodeUnavailable, to 3
+// for failures of the service itself, such as CodeInternal. Codes that are
+// not defined by this package rank as CodeUnknown does.
+func CodeSeverity(c ErrorCode) int {
+	switch c {
+	case CodeOK:
+		return 0
+	case CodeCanceled, CodeInvalidArgument, CodeNotFound, CodeAlreadyExists,
+		CodePermissionDenied, CodeFailedPrecondition, CodeAborted,
+		CodeOutOfRange, CodeUnauthenticated:
+		return 1
+	case CodeDeadlineExceeded, CodeResourceExhausted, CodeUnavailable:
+		return 2
+	}
+	return 3
+}
+
+type withCode struct {
+	annotation
+	code ErrorCode
+}
+
+func (w *withCode) rewrap(cause error) error {
+	c := *w
+	c.cause = cause
+	return &c
+}
+
+// NotFoundf returns an error tagged with CodeNotFound, formatted as Errorf
+// does. NotFoundf also records the stack trace at the point it was called.
+func NotFoundf(format string, args ...interface{}) error {
+	return globalErrorsApi().NotFoundf(format, args...)
+}
+
+// InvalidArgumentf returns an error tagged with CodeInvalidArgument,
+// formatted as Errorf does. InvalidArgumentf also records the stack trace at
+// the point it was called.
+func InvalidArgumentf(format string, args ...interface{}) error {
+	return globalErrorsApi().InvalidArgumentf(format, args...)
+}
+
+// Unimplementedf returns an error tagged with CodeUnimplemented, formatted
+// as Errorf does. Unimplementedf also records the stack trace at the point
+// it was called.
+func Unimplementedf(format string, args ...interface{}) error {
+	return globalErrorsApi().Unimplementedf(format, args...)
+}
+
+// Internalf returns an error tagged with CodeInternal, formatted as Errorf
+// does. Internalf also records the stack trace at the point it was called.
+func Internalf(format string, args ...interface{}) error {
+	return globalErrorsApi().Internalf(format, args...)
+}
+
+// IsNotFound reports whether the code of err is CodeNotFound.
+func IsNotFound(err error) bool { return Code(err) == CodeNotFound }
+
+// IsInvalidArgument reports whether the code of err is CodeInvalidArgument.
+func IsInvalidArgument(err error) bool { return Code(err) == CodeInvalidArgument }
+
+// IsUnimplemented reports whether the code of err is CodeUnimplemented.
+func IsUnimplemented(err error) bool { return Code(err) == CodeUnimplemented }
+
+// IsInternal reports whether the code of err is CodeInternal.
+func IsInternal(err error) bool { return Code(err) == CodeInternal }
+
+func (e *errorsApi) NotFoundf(format string, args ...interface{}) error {
+	return e.codef(1, CodeNotFound, format, args)
+}
+
+func (e *errorsApi) InvalidArgumentf(format string, args ...interface{}) error {
+	return e.codef(1, CodeInvalidArgument, format, args)
+}
+
+func (e *errorsApi) Unimplementedf(format string, args ...interface{}) error {
+	return e.codef(1, CodeUnimplemented, format, args)
+}
+
+func (e *errorsApi) Internalf(format string, args ...interface{}) error {
+	return e.codef(1, CodeInternal, format, args)
+}
+
+// codef implements the coded constructors for callers that are skip frames
+// below the api method called by the user.
+func (e *errorsApi) codef(skip int, code ErrorCode, format string, args []interface{}) error {
+	st := e.callers(skip)
+	return e.created(&withCode{annotation{e.fundamental(sprintf(format, args), st, &msgTemplate{format, args})}, code}, st)
+}
+
+func (e *errorsApi) WithCode(err error, code ErrorCode) error {
+	if err == nil {
+		return nil
+	}
+	return &withCode{annotation{err}, code}
+}
@@ -0,0 +1,90 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorCode
+	}{
+		{nil, CodeOK},
+		{io.EOF, CodeUnknown},
+		{WithCode(io.EOF, CodeNotFound), CodeNotFound},
+		{Wrap(WithCode(io.EOF, CodeNotFound), "wrapped"), CodeNotFound},
+		{WithCode(WithCode(io.EOF, CodeNotFound), CodeInternal), CodeInternal},
+		{fmt.Errorf("foreign: %w", NotFoundf("user %d", 1)), CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Code(tt.err), "Code(%v)", tt.err)
+	}
+	assert.Nil(t, WithCode(nil, CodeNotFound))
+}
+
+func TestCodeString(t *testing.T) {
+	assert.Equal(t, "NotFound", CodeNotFound.String())
+	assert.Equal(t, "Unauthenticated", CodeUnauthenticated.String())
+	assert.Equal(t, "Code(100)", ErrorCode(100).String())
+}
+
+func TestCodeConstructors(t *testing.T) {
+	tests := []struct {
+		err  error
+		is   func(error) bool
+		code ErrorCode
+	}{
+		{NotFoundf("user %d", 1), IsNotFound, CodeNotFound},
+		{InvalidArgumentf("user %d", 1), IsInvalidArgument, CodeInvalidArgument},
+		{Unimplementedf("user %d", 1), IsUnimplemented, CodeUnimplemented},
+		{Internalf("user %d", 1), IsInternal, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, "user 1", tt.err.Error())
+		assert.Equal(t, tt.code, Code(tt.err))
+		assert.True(t, tt.is(tt.err))
+		assert.True(t, tt.is(Wrap(tt.err, "wrapped")))
+		assert.False(t, tt.is(io.EOF))
+		matchLines(t, []string{"^user 1\ngithub.com/pkg/errors.TestCodeConstructors\t.+/github.com/pkg/errors/code_test.go:\\d+$"}, Lines(tt.err, true))
+	}
+}
+
+func TestCodeFormat(t *testing.T) {
+	err := WithCode(Wrap(io.EOF, "read"), CodeDataLoss)
+	assert.Equal(t, "read: EOF", fmt.Sprintf("%s", err))
+	assert.Equal(t, "read: EOF", fmt.Sprintf("%v", err))
+	assert.Equal(t, `"read: EOF"`, fmt.Sprintf("%q", err))
+	assert.Regexp(t, "^EOF\nread\ngithub.com/pkg/errors.TestCodeFormat\t.+/github.com/pkg/errors/code_test.go:\\d+$", fmt.Sprintf("%+v", err))
+	assert.Equal(t, "outer: read: EOF", WithMessage(err, "outer").Error())
+}
+
+func TestCodePolicy(t *testing.T) {
+	defer SetCodePolicy(CodeOutermost)
+	err := WithCode(Wrap(WithCode(WithCode(io.EOF, CodeNotFound), CodeInternal), "read"), CodeUnavailable)
+	assert.Equal(t, []ErrorCode{CodeUnavailable, CodeInternal, CodeNotFound}, AllCodes(err))
+	assert.Nil(t, AllCodes(io.EOF))
+
+	tests := []struct {
+		policy CodePolicy
+		want   ErrorCode
+	}{
+		{CodeOutermost, CodeUnavailable},
+		{CodeInnermost, CodeNotFound},
+		{CodeMostSevere, CodeInternal},
+	}
+	for _, tt := range tests {
+		SetCodePolicy(tt.policy)
+		assert.Equal(t, tt.want, Code(err), "policy %d", tt.policy)
+		assert.Equal(t, CodeUnknown, Code(io.EOF), "policy %d", tt.policy)
+		assert.Equal(t, CodeOK, Code(nil), "policy %d", tt.policy)
+	}
+
+	SetCodePolicy(CodeMostSevere)
+	assert.Equal(t, CodeNotFound, Code(WithCode(WithCode(io.EOF, CodeAlreadyExists), CodeNotFound)))
+}
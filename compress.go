@@ -0,0 +1,128 @@
+package errors
+
+import (
+	"hash/fnv"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// CompressedStack returns a one-line summary of the stack trace of the
+// outermost error in err's chain that has one, for logs written on every
+// request: a fingerprint of the whole stack followed by its top application
+// frame, as in
+//
+//	9c6e4d2f0b1a8e37 example.com/app.(*Server).handle server.go:42
+//
+// The fingerprint only depends on the function names and line numbers of
+// the frames, so errors raised along the same path share it across
+// processes and build directories. The top application frame is the
+// first one outside the standard library. CompressedStack returns an empty
+// string if err has no stack trace.
+func CompressedStack(err error) string {
+	st := nearestStack(err)
+	if len(st) == 0 {
+		return ""
+	}
+	return stackFingerprint(st) + " " + frameSummary(topFrame(st))
+}
+
+// topFrame returns the first frame of st outside the standard library, or
+// the first frame if there is none.
+func topFrame(st StackTrace) Frame {
+	for _, f := range st {
+		if !isStdFrame(f) {
+			return f
+		}
+	}
+	return st[0]
+}
+
+// frameSummary formats f as its function name followed by the base name
+// of its file and its line, as in "example.com/app.run main.go:12".
+func frameSummary(f Frame) string {
+	return f.name() + " " + path.Base(f.file()) + ":" + strconv.Itoa(f.line())
+}
+
+// stackFingerprint returns a hash of keys, which tell apart errors raised
+// along the same stack, and of the function names and line numbers of st,
+// as 16 hexadecimal digits.
+func stackFingerprint(st StackTrace, keys ...string) string {
+	h := fnv.New64a()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{'\n'})
+	}
+	for _, f := range st {
+		h.Write([]byte(f.name()))
+		h.Write([]byte{':'})
+		h.Write([]byte(strconv.Itoa(f.line())))
+		h.Write([]byte{'\n'})
+	}
+	s := strconv.FormatUint(h.Sum64(), 16)
+	return strings.Repeat("0", 16-len(s)) + s
+}
+
+// isStdFrame reports whether f belongs to the standard library, whose
+// import paths have no dot in their first element.
+func isStdFrame(f Frame) bool {
+	name := f.name()
+	if name == "unknown" {
+		return true
+	}
+	pkg := name
+	slash := strings.LastIndexByte(name, '/')
+	if dot := strings.IndexByte(name[slash+1:], '.'); dot >= 0 {
+		pkg = name[:slash+1+dot]
+	}
+	if pkg == "main" {
+		return false
+	}
+	if i := strings.IndexByte(pkg, '/'); i >= 0 {
+		pkg = pkg[:i]
+	}
+	return !strings.Contains(pkg, ".")
+}
+
+// StackSampler chooses between full and compressed stack traces for logs
+// written on every request, so that the full stacks of frequent errors do
+// not take up the log volume. The zero value logs full stacks only.
+type StackSampler struct {
+	every uint64
+	seen  sync.Map // fingerprint -> *uint64
+}
+
+// NewStackSampler returns a sampler that logs the full stack of one in
+// every errors with the same stack fingerprint, starting with the first.
+func NewStackSampler(every int) *StackSampler {
+	if every < 1 {
+		every = 1
+	}
+	return &StackSampler{every: uint64(every)}
+}
+
+// Stack returns the stack trace of err for a log entry: the stack trace of
+// the outermost error in err's chain that has one, as returned by its
+// String method with the options of the api that created the error, if it
+// is sampled, and CompressedStack(err) otherwise.
+// Stack returns an empty string if err has no stack trace.
+func (s *StackSampler) Stack(err error) string {
+	st, opts := nearestStackFormat(err)
+	if len(st) == 0 {
+		return ""
+	}
+	if s.every <= 1 {
+		return st.stringWith(formatOptions(opts))
+	}
+	fp := stackFingerprint(st)
+	n, ok := s.seen.Load(fp)
+	if !ok {
+		n, _ = s.seen.LoadOrStore(fp, new(uint64))
+	}
+	if (atomic.AddUint64(n.(*uint64), 1)-1)%s.every == 0 {
+		return st.stringWith(formatOptions(opts))
+	}
+	return CompressedStack(err)
+}
@@ -0,0 +1,54 @@
+package errors
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressedStack(t *testing.T) {
+	assert.Equal(t, "", CompressedStack(nil))
+	assert.Equal(t, "", CompressedStack(io.EOF))
+
+	api := NewErrorsApi(WithMaxDepth(8))
+	errs := make([]error, 2)
+	for i := range errs {
+		errs[i] = api.New("foo")
+	}
+	got := CompressedStack(WithMessage(errs[0], "bar"))
+	assert.Regexp(t, "^[0-9a-f]{16} github.com/pkg/errors.TestCompressedStack compress_test.go:19$", got)
+	assert.Equal(t, got, CompressedStack(errs[1]))
+	assert.NotEqual(t, got[:16], CompressedStack(api.New("baz"))[:16])
+}
+
+func TestIsStdFrame(t *testing.T) {
+	st := NewErrorsApi(WithMaxDepth(8)).New("foo").(*fundamental).StackTrace()
+	assert.False(t, isStdFrame(st[0]))
+	assert.True(t, isStdFrame(st[1]))
+	assert.True(t, isStdFrame(0))
+}
+
+func TestStackSampler(t *testing.T) {
+	assert.Equal(t, "", NewStackSampler(2).Stack(io.EOF))
+
+	full := regexp.MustCompile("^github.com/pkg/errors.TestStackSampler\t.+/github.com/pkg/errors/compress_test.go:\\d+$")
+	s := NewStackSampler(3)
+	var errs []error
+	var got []string
+	for i := 0; i < 4; i++ {
+		errs = append(errs, New("foo"))
+		got = append(got, s.Stack(errs[i]))
+	}
+	other := s.Stack(New("bar"))
+	assert.Regexp(t, full, got[0])
+	assert.Equal(t, CompressedStack(errs[1]), got[1])
+	assert.Equal(t, got[1], got[2])
+	assert.Regexp(t, full, got[3])
+	assert.Regexp(t, full, other)
+
+	var zero StackSampler
+	assert.True(t, strings.HasPrefix(zero.Stack(New("foo")), "github.com/pkg/errors.TestStackSampler\t"))
+}
@@ -1,94 +1,751 @@
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
 
 type ApiConfig struct {
 	CallerSkip int
+	// Depth is the maximum number of frames captured per stack trace.
+	// Zero selects DefaultDepth.
+	Depth int
+	// SkipRedundantStack makes Wrap, Wrapf and WithStack skip capturing a
+	// stack when the error they annotate already carries one. Wrap and
+	// Wrapf still add their message; WithStack returns the error as is.
+	SkipRedundantStack bool
+	// DedupMessages makes wrappers drop their message when the message of
+	// their cause is the same or starts with it, so that Error() does not
+	// read "context canceled: context canceled". The wrapper keeps its
+	// stack. Comparing the messages composes the cause's Error() string
+	// whenever a wrapper is created.
+	DedupMessages bool
+	// LocationPrefix makes Wrap, Wrapf and their conditional variants
+	// prefix their message with the file name and line they were called
+	// at, as in "config.go:42: loading config", so that the location shows
+	// even where errors are logged without their stacks.
+	LocationPrefix bool
+	// ErrorString selects when wrappers compose their Error() string.
+	ErrorString ErrorStringMode
+	// Options configure how the errors created by the api are formatted.
+	// They are applied on top of the default separators when the api is
+	// created, and make its errors independent of SetOptions. Without
+	// Options, errors follow the package level options.
+	Options []Option
+	// DisableStack makes the api create errors without stack traces, for
+	// hot paths that cannot afford to capture them. WithStack returns its
+	// argument as is, and Wrap and Wrapf only add their message.
+	DisableStack bool
+	// Clock returns the time recorded by WithTime. Nil selects time.Now.
+	Clock func() time.Time
+	// TraceExtractor reads the trace and span IDs WrapCtx attaches. Nil
+	// selects TraceparentFromContext.
+	TraceExtractor TraceExtractor
+	// Hooks are registered on the api when it is created; see OnNew and
+	// OnWrap.
+	Hooks Hooks
+	// FrameFilter, if not nil, is called for every frame while a stack is
+	// captured. Frames it returns false for are left out, and do not count
+	// towards Depth.
+	FrameFilter func(Frame) bool
+	// PprofLabels makes WrapCtx attach the pprof labels of its context, as
+	// set by pprof.Do, as details; see WithPprofLabels.
+	PprofLabels bool
+	// StackRules override Depth and DisableStack for errors created in the
+	// packages they name; see WithStackRules.
+	StackRules []StackRule
+	// MaxChainDepth, if positive, bounds the number of layers Wrap, Wrapf,
+	// WithMessage, WithStack, Check and their variants add to a chain.
+	// Once a chain is that deep, they only count their calls, under
+	// CollapsedWrapsKey, so that a loop that keeps wrapping an error cannot
+	// grow it without bound.
+	MaxChainDepth int
+	// MaxErrorLength, if positive, caps the length in bytes of the Error()
+	// strings of the errors created by the api, so that an error that
+	// quotes a huge response body cannot flood the logs and services it is
+	// passed to. Longer strings are cut and end with a marker telling how
+	// many bytes were left out. Formatting with %+v is not affected.
+	MaxErrorLength int
+	// Translator, if not nil, is called with the message of every layer
+	// the api creates whenever it is rendered, by Error(), the fmt verbs,
+	// Lines and the other renderings of this package, and returns the
+	// message to render instead: to translate it, normalize its terms or
+	// tag it with an incident ID. It is not called for empty messages, nor
+	// for the layers created by other apis in the same chain, which are
+	// rendered by their own translators. It must be safe for concurrent
+	// use.
+	Translator func(msg string) string
+	// WrapNilAsNew makes Wrap, Wrapf, WrapCtx and their variants create
+	// a new error from their message, as New does, when the error they
+	// are given is nil, instead of returning nil, so that annotations are
+	// not silently dropped when a nil error reaches them by mistake.
+	WrapNilAsNew bool
+	// PayloadLimit is the number of bytes of a payload WithPayload keeps.
+	// Zero selects DefaultPayloadLimit, and a negative limit keeps none,
+	// only the size of the payload.
+	PayloadLimit int
+	// PayloadRedactor, if not nil, is called by WithPayload with the name
+	// and the whole body of a payload before it is cut, and returns the
+	// body with the secrets it holds, such as tokens and passwords, masked.
+	// It must not modify body, which belongs to the caller.
+	PayloadRedactor func(name string, body []byte) []byte
 }
 
+// ApiOption configures the api created by NewErrorsApi. An ApiConfig is
+// itself an ApiOption that replaces the whole configuration, so that
+// NewErrorsApi(ApiConfig{...}) keeps working, and may be followed by other
+// options that adjust it.
+type ApiOption interface {
+	apply(*ApiConfig)
+}
+
+func (c ApiConfig) apply(cfg *ApiConfig) {
+	*cfg = c
+}
+
+type apiOptionFunc func(*ApiConfig)
+
+func (f apiOptionFunc) apply(cfg *ApiConfig) {
+	f(cfg)
+}
+
+// WithCallerSkip sets the number of frames between the user's code and the
+// api methods. It is 1, the default, for code that calls the methods
+// directly, and one more for every function in between.
+func WithCallerSkip(skip int) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.CallerSkip = skip
+	})
+}
+
+// AddCallerSkip adds skip to the caller skip of the api being configured.
+// It is meant for Derive, to account for the frames of a helper that
+// creates errors on behalf of its callers.
+func AddCallerSkip(skip int) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.CallerSkip += skip
+	})
+}
+
+// WithMaxDepth sets the maximum number of frames captured per stack trace.
+func WithMaxDepth(depth int) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.Depth = depth
+	})
+}
+
+// WithFrameFilter sets the filter applied to frames as stacks are captured.
+func WithFrameFilter(keep func(Frame) bool) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.FrameFilter = keep
+	})
+}
+
+// WithDisableStack sets ApiConfig.DisableStack.
+func WithDisableStack(disable bool) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.DisableStack = disable
+	})
+}
+
+// WithSkipRedundantStack sets ApiConfig.SkipRedundantStack.
+func WithSkipRedundantStack(skip bool) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.SkipRedundantStack = skip
+	})
+}
+
+// WithDedupMessages sets ApiConfig.DedupMessages.
+func WithDedupMessages(dedup bool) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.DedupMessages = dedup
+	})
+}
+
+// WithLocationPrefix sets ApiConfig.LocationPrefix.
+func WithLocationPrefix(prefix bool) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.LocationPrefix = prefix
+	})
+}
+
+// WithErrorString sets when wrappers compose their Error() string.
+func WithErrorString(mode ErrorStringMode) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.ErrorString = mode
+	})
+}
+
+// WithMaxErrorLength sets ApiConfig.MaxErrorLength.
+func WithMaxErrorLength(n int) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.MaxErrorLength = n
+	})
+}
+
+// WithWrapNilAsNew sets ApiConfig.WrapNilAsNew.
+func WithWrapNilAsNew(asNew bool) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.WrapNilAsNew = asNew
+	})
+}
+
+// WithTranslator sets ApiConfig.Translator.
+func WithTranslator(translate func(msg string) string) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.Translator = translate
+	})
+}
+
+// WithFormatOptions adds options that configure how the errors created by
+// the api are formatted; see ApiConfig.Options.
+func WithFormatOptions(options ...Option) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.Options = append(c.Options[:len(c.Options):len(c.Options)], options...)
+	})
+}
+
+// ErrorStringMode selects when the Error() string of a wrapper is composed
+// from its message and the messages of its causes.
+type ErrorStringMode int
+
+const (
+	// ErrorStringOnDemand composes the string on every call to Error().
+	ErrorStringOnDemand ErrorStringMode = iota
+	// ErrorStringEager composes the string when the error is created and
+	// caches it, for errors that are logged many times.
+	ErrorStringEager
+	// ErrorStringLazy composes the string on the first call to Error() and
+	// caches it, so errors that are discarded never pay for it.
+	ErrorStringLazy
+)
+
 type errorsApi struct {
-	cfg ApiConfig
+	cfg    ApiConfig
+	format *Config
+	hooks  *apiHooks
+	rules  *stackRules
+	render *renderConfig
 }
 
-func NewErrorsApi(cfg ApiConfig) *errorsApi {
-	return &errorsApi{
-		cfg: cfg,
+// NewErrorsApi returns an api configured by opts. Without options, it
+// captures one frame per stack trace, starting at the code that calls its
+// methods.
+func NewErrorsApi(opts ...ApiOption) *errorsApi {
+	cfg := ApiConfig{
+		CallerSkip: 1,
+	}
+	for _, opt := range opts {
+		opt.apply(&cfg)
 	}
+	return newErrorsApi(cfg, nil)
 }
 
-var globalErrorsApi = NewErrorsApi(ApiConfig{
-	CallerSkip: 2,
-})
+// newErrorsApi returns an api configured by cfg, whose hooks run before
+// those in parent.
+func newErrorsApi(cfg ApiConfig, parent *apiHooks) *errorsApi {
+	e := &errorsApi{
+		hooks: &apiHooks{parent: parent},
+	}
+	e.render = newRenderConfig(cfg)
+	if len(cfg.StackRules) > 0 {
+		e.rules = newStackRules(cfg.StackRules)
+	}
+	if len(cfg.Options) > 0 {
+		e.format = defaultConfig()
+		for _, option := range cfg.Options {
+			option(e.format)
+		}
+	}
+	for _, hook := range cfg.Hooks.OnNew {
+		e.OnNew(hook)
+	}
+	for _, hook := range cfg.Hooks.OnWrap {
+		e.OnWrap(hook)
+	}
+	// The hooks now live in e.hooks, where they are shared with the apis
+	// derived from e, which must not register them again.
+	cfg.Hooks = Hooks{}
+	e.cfg = cfg
+	return e
+}
 
-func (e *errorsApi) New(message string) error {
-	return &fundamental{
-		msg:   message,
-		stack: callers(e.cfg.CallerSkip),
+// Derive returns a child of e, configured like e with opts applied on top.
+// The child shares the hooks of e, including those registered on e later,
+// while hooks added to the child only apply to the child.
+// A helper package that creates errors on behalf of its callers can derive
+// an api with AddCallerSkip, so that stacks start at its callers:
+//
+//	var api = errors.NewErrorsApi().Derive(errors.AddCallerSkip(1))
+//
+//	func fail(op string) error {
+//	        return api.Errorf("%s failed", op)
+//	}
+func (e *errorsApi) Derive(opts ...ApiOption) *errorsApi {
+	cfg := e.cfg
+	for _, opt := range opts {
+		opt.apply(&cfg)
 	}
+	return newErrorsApi(cfg, e.hooks)
 }
 
-func (e *errorsApi) Errorf(format string, args ...interface{}) error {
-	return &fundamental{
-		msg:   fmt.Sprintf(format, args...),
-		stack: callers(e.cfg.CallerSkip),
+// ErrorsApi is the set of constructors behind the package level functions.
+// It is implemented by the values NewErrorsApi returns, and may be
+// implemented by applications that want to instrument or customize the
+// errors created through this package; see SetGlobalApi.
+type ErrorsApi interface {
+	New(message string) error
+	Errorf(format string, args ...interface{}) error
+	NewPlain(message string) error
+	WithStack(err error) error
+	Wrap(err error, message string) error
+	Wrapf(err error, format string, args ...interface{}) error
+	WrapIf(cond bool, err error, message string) error
+	WrapfIf(cond bool, err error, format string, args ...interface{}) error
+	WrapFn(err error) error
+	WrapPlain(err error, message string) error
+	WithMessage(err error, message string) error
+	WithMessagef(err error, format string, args ...interface{}) error
+}
+
+var _ ErrorsApi = (*errorsApi)(nil)
+
+var defaultErrorsApi = NewErrorsApi(WithCallerSkip(2))
+
+// globalApis holds the *apis installed by SetGlobalApi.
+var globalApis atomic.Value
+
+type apis struct {
+	api  ErrorsApi
+	impl *errorsApi
+}
+
+func init() {
+	globalApis.Store(&apis{defaultErrorsApi, defaultErrorsApi})
+}
+
+// SetGlobalApi replaces the ErrorsApi behind the package level
+// constructors, such as New, Wrap and WithMessage. A nil api restores the
+// default one.
+//
+// The methods of api are called directly by the package level functions,
+// two frames below the user's code, which is what CallerSkip 2 accounts for
+// in an api created by NewErrorsApi. Implementations that decorate such an
+// api must add one to CallerSkip for every frame they add in between.
+//
+// Helpers that are not part of ErrorsApi, such as Wrap1, Check and the
+// coded constructors, use api if it was created by NewErrorsApi, and the
+// default api otherwise.
+func SetGlobalApi(api ErrorsApi) {
+	if api == nil {
+		api = defaultErrorsApi
+	}
+	impl, ok := api.(*errorsApi)
+	if !ok {
+		impl = defaultErrorsApi
+	}
+	globalApis.Store(&apis{api, impl})
+}
+
+// SnapshotGlobalApi records the api installed by SetGlobalApi and returns a
+// function that reinstalls it, for tests to defer as they do SnapshotOptions.
+func SnapshotGlobalApi() (restore func()) {
+	a := globalApis.Load().(*apis)
+	return func() {
+		globalApis.Store(a)
+	}
+}
+
+// GlobalApi returns the ErrorsApi installed by SetGlobalApi, for packages
+// that build their own constructors on it. A function that calls its
+// methods directly on behalf of its own caller, as New does, gets stack
+// traces that start at that caller.
+func GlobalApi() ErrorsApi {
+	return globalApi()
+}
+
+// globalApi returns the ErrorsApi behind the package level constructors.
+func globalApi() ErrorsApi {
+	return globalApis.Load().(*apis).api
+}
+
+// globalErrorsApi returns the *errorsApi behind the package level helpers
+// that are not part of ErrorsApi.
+func globalErrorsApi() *errorsApi {
+	return globalApis.Load().(*apis).impl
+}
+
+// callers captures the stack of the user code that called the api method
+// which is skip frames above the caller of callers. It returns nil if the
+// api has stacks disabled.
+func (e *errorsApi) callers(skip int) *stack {
+	disable, depth := e.cfg.DisableStack, e.cfg.Depth
+	if e.rules != nil {
+		if rule := e.rules.lookup(e.cfg.CallerSkip + skip + 1); rule != nil {
+			disable, depth = rule.DisableStack, rule.Depth
+		}
+	}
+	if disable {
+		return nil
+	}
+	if e.cfg.FrameFilter != nil {
+		return filteredCallers(e.cfg.CallerSkip+skip+1, depth, e.cfg.FrameFilter)
+	}
+	return callers(e.cfg.CallerSkip+skip+1, depth)
+}
+
+// hasStack reports whether any error in err's chain carries a stack trace.
+func hasStack(err error) bool {
+	return len(nearestStack(err)) > 0
+}
+
+// nearestStack returns the stack trace of the outermost error in err's
+// chain that carries one. The layers of this package are asked for their
+// own stack only, so that walking a chain of them does not make each one
+// walk the rest of the chain again.
+func nearestStack(err error) StackTrace {
+	st, _ := nearestStackFormat(err)
+	return st
+}
+
+// nearestStackFormat is like nearestStack, but also returns the options of
+// the api that created the error carrying the stack, or nil if it has none
+// of its own, for the stack to be rendered with them.
+func nearestStackFormat(err error) (StackTrace, *Config) {
+	for ; err != nil; err = unwrapOnce(err) {
+		switch e := err.(type) {
+		case layer:
+			if st := e.layerStack(); st != nil && len(*st) > 0 {
+				return st.StackTrace(), e.layerFormat()
+			}
+		case interface{ StackTrace() StackTrace }:
+			if st := e.StackTrace(); len(st) > 0 {
+				return st, nil
+			}
+		}
 	}
+	return nil, nil
+}
+
+func (e *errorsApi) New(message string) error {
+	st := e.callers(0)
+	return e.created(e.fundamental(message, st, nil), st)
+}
+
+func (e *errorsApi) Errorf(format string, args ...interface{}) error {
+	st := e.callers(0)
+	return e.created(e.fundamental(sprintf(format, args), st, &msgTemplate{format, args}), st)
+}
+
+func (e *errorsApi) NewPlain(message string) error {
+	return e.created(e.fundamental(message, nil, nil), nil)
 }
 
 func (e *errorsApi) WithStack(err error) error {
 	if err == nil {
 		return nil
 	}
-	return &withStack{
-		withMessage{
-			cause: err,
-			msg:   "",
-		},
-		callers(e.cfg.CallerSkip),
+	if e.cfg.SkipRedundantStack && hasStack(err) {
+		return err
 	}
+	if c, ok := e.collapse(err); ok {
+		return c
+	}
+	return e.withStack(err, "", nil, e.callers(0))
+}
+
+func (e *errorsApi) WithStackTrace(err error, st StackTrace) error {
+	if err == nil || len(st) == 0 {
+		return err
+	}
+	if c, ok := e.collapse(err); ok {
+		return c
+	}
+	s := make(stack, len(st))
+	for i, f := range st {
+		s[i] = uintptr(f)
+	}
+	return e.withStack(err, "", nil, &s)
 }
 
 func (e *errorsApi) Wrap(err error, message string) error {
+	return e.wrap(1, err, message)
+}
+
+func (e *errorsApi) Wrapf(err error, format string, args ...interface{}) error {
+	if err == nil && !e.cfg.WrapNilAsNew {
+		return nil
+	}
+	return e.wrapf(1, err, format, args)
+}
+
+// wrap implements Wrap for callers that are skip frames below the api
+// method called by the user.
+func (e *errorsApi) wrap(skip int, err error, message string) error {
+	return e.wrapWith(skip+1, err, message, nil)
+}
+
+// wrapf implements Wrapf for callers that are skip frames below the api
+// method called by the user.
+func (e *errorsApi) wrapf(skip int, err error, format string, args []interface{}) error {
+	return e.wrapWith(skip+1, err, sprintf(format, args), &msgTemplate{format, args})
+}
+
+// wrapWith annotates err with message, rendered from tmpl if it is not
+// nil, for callers that are skip frames below the api method called by the
+// user.
+func (e *errorsApi) wrapWith(skip int, err error, message string, tmpl *msgTemplate) error {
+	if err == nil {
+		if !e.cfg.WrapNilAsNew {
+			return nil
+		}
+		st := e.callers(skip)
+		return e.created(e.fundamental(message, st, tmpl), st)
+	}
+	if c, ok := e.collapse(err); ok {
+		return c
+	}
+	if !e.cfg.LocationPrefix {
+		if e.cfg.SkipRedundantStack && hasStack(err) {
+			return e.withMessage(err, message, tmpl)
+		}
+		return e.withStack(err, message, tmpl, e.callers(skip))
+	}
+	var st *stack
+	if !e.cfg.SkipRedundantStack || !hasStack(err) {
+		st = e.callers(skip)
+	}
+	loc := st
+	if loc == nil {
+		// No stack is captured, but the location is still needed.
+		loc = callers(e.cfg.CallerSkip+skip, 1)
+	}
+	message = prefixLocation(loc, message)
+	if st == nil {
+		return e.withMessage(err, message, tmpl)
+	}
+	return e.withStack(err, message, tmpl, st)
+}
+
+// prefixLocation prefixes message with the file name and line of the first
+// frame of st, as in "config.go:42: loading config".
+func prefixLocation(st *stack, message string) string {
+	if len(*st) == 0 {
+		return message
+	}
+	f := Frame((*st)[0])
+	loc := path.Base(f.file()) + ":" + strconv.Itoa(f.line())
+	if message == "" {
+		return loc
+	}
+	return loc + ": " + message
+}
+
+func (e *errorsApi) WrapIf(cond bool, err error, message string) error {
+	if !cond {
+		return err
+	}
+	return e.wrap(1, err, message)
+}
+
+func (e *errorsApi) WrapfIf(cond bool, err error, format string, args ...interface{}) error {
+	if !cond || err == nil && !e.cfg.WrapNilAsNew {
+		return err
+	}
+	return e.wrapf(1, err, format, args)
+}
+
+func (e *errorsApi) WrapOrNew(err error, message string) error {
+	if err == nil {
+		st := e.callers(0)
+		return e.created(e.fundamental(message, st, nil), st)
+	}
+	return e.wrap(1, err, message)
+}
+
+func (e *errorsApi) WrapFn(err error) error {
 	if err == nil {
 		return nil
 	}
-	return &withStack{
-		withMessage{
-			cause: err,
-			msg:   message,
-		},
-		callers(e.cfg.CallerSkip),
+	if c, ok := e.collapse(err); ok {
+		return c
+	}
+	st := e.callers(0)
+	frames := st
+	if frames == nil {
+		// Stacks are disabled, but the name of the caller is still needed.
+		frames = callers(e.cfg.CallerSkip, 1)
+	}
+	name := "unknown"
+	if len(*frames) > 0 {
+		name = pkgFuncname(Frame((*frames)[0]).name())
 	}
+	if e.cfg.SkipRedundantStack && hasStack(err) {
+		return e.withMessage(err, name, nil)
+	}
+	return e.withStack(err, name, nil, st)
 }
 
-func (e *errorsApi) Wrapf(err error, format string, args ...interface{}) error {
+func (e *errorsApi) WrapPlain(err error, message string) error {
 	if err == nil {
 		return nil
 	}
-	return &withStack{
-		withMessage{
-			cause: err,
-			msg:   fmt.Sprintf(format, args...),
-		},
-		callers(e.cfg.CallerSkip),
+	if c, ok := e.collapse(err); ok {
+		return c
 	}
+	return e.withMessage(err, message, nil)
 }
 
 func (e *errorsApi) WithMessage(err error, message string) error {
 	if err == nil {
 		return nil
 	}
-	return &withMessage{
-		cause: err,
-		msg:   message,
+	if c, ok := e.collapse(err); ok {
+		return c
 	}
+	return e.withMessage(err, message, nil)
 }
 
 func (e *errorsApi) WithMessagef(err error, format string, args ...interface{}) error {
 	if err == nil {
 		return nil
 	}
-	return &withMessage{
-		cause: err,
-		msg:   fmt.Sprintf(format, args...),
+	if c, ok := e.collapse(err); ok {
+		return c
+	}
+	return e.withMessage(err, sprintf(format, args), &msgTemplate{format, args})
+}
+
+// fundamental returns a new error with message and st. If tmpl is not
+// nil, message was rendered from it.
+func (e *errorsApi) fundamental(message string, st *stack, tmpl *msgTemplate) *fundamental {
+	return &fundamental{
+		msg:    message,
+		stack:  st,
+		format: e.format,
+		render: e.render,
+		tmpl:   tmpl.of(message),
+	}
+}
+
+// withMessage annotates cause with message. If tmpl is not nil, message
+// was rendered from it.
+func (e *errorsApi) withMessage(cause error, message string, tmpl *msgTemplate) *withMessage {
+	w := &withMessage{
+		cause:  cause,
+		msg:    e.dedupMessage(cause, message),
+		format: e.format,
+		render: e.render,
+	}
+	if w.msg != "" {
+		w.tmpl = tmpl.of(w.msg)
+	}
+	e.cacheError(w)
+	e.wrapped(w, nil)
+	return w
+}
+
+// withStack annotates cause with message and st. If st is nil, because the
+// api has stacks disabled, it only adds the message, if there is one.
+func (e *errorsApi) withStack(cause error, message string, tmpl *msgTemplate, st *stack) error {
+	if st == nil {
+		if message == "" {
+			return cause
+		}
+		return e.withMessage(cause, message, tmpl)
+	}
+	w := &withStack{
+		withMessage{
+			cause:  cause,
+			msg:    e.dedupMessage(cause, message),
+			format: e.format,
+			render: e.render,
+		},
+		st,
+	}
+	if w.msg != "" {
+		w.tmpl = tmpl.of(w.msg)
+	}
+	e.cacheError(&w.withMessage)
+	e.wrapped(w, st)
+	return w
+}
+
+// dedupMessage returns message, or nothing if the api has DedupMessages
+// set and the message of cause is message or starts with it.
+func (e *errorsApi) dedupMessage(cause error, message string) string {
+	if !e.cfg.DedupMessages || message == "" || cause == nil {
+		return message
+	}
+	s := cause.Error()
+	if strings.HasPrefix(s, message) && (len(s) == len(message) || strings.HasPrefix(s[len(message):], ": ")) {
+		return ""
+	}
+	return message
+}
+
+// cacheError prepares w to cache its Error() string as selected by the
+// api's ErrorString mode.
+func (e *errorsApi) cacheError(w *withMessage) {
+	switch e.cfg.ErrorString {
+	case ErrorStringEager:
+		w.cache = &errorCache{}
+		w.cachedError()
+	case ErrorStringLazy:
+		w.cache = &errorCache{}
+	}
+}
+
+// renderConfig holds the settings of an api that apply as the errors it
+// created are rendered. It is nil for apis that have none.
+type renderConfig struct {
+	maxLength int
+	translate func(msg string) string
+}
+
+func newRenderConfig(cfg ApiConfig) *renderConfig {
+	if cfg.MaxErrorLength <= 0 && cfg.Translator == nil {
+		return nil
+	}
+	return &renderConfig{cfg.MaxErrorLength, cfg.Translator}
+}
+
+// message returns msg, the message of a layer, as it is rendered.
+func (r *renderConfig) message(msg string) string {
+	if r == nil || r.translate == nil || msg == "" {
+		return msg
+	}
+	return r.translate(msg)
+}
+
+// truncates reports whether r caps the length of Error() strings.
+func (r *renderConfig) truncates() bool {
+	return r != nil && r.maxLength > 0
+}
+
+// truncate cuts s, an Error() string, to the maximum length, on a rune
+// boundary, and marks how many bytes were cut, if s is longer.
+func (r *renderConfig) truncate(s string) string {
+	if !r.truncates() || len(s) <= r.maxLength {
+		return s
+	}
+	n := r.maxLength
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "... (" + strconv.Itoa(len(s)-n) + " more bytes)"
+}
+
+// sprintf is fmt.Sprintf with a fast path for formats without verbs that
+// are called without arguments, which are returned as they are.
+func sprintf(format string, args []interface{}) string {
+	if len(args) == 0 && strings.IndexByte(format, '%') < 0 {
+		return format
 	}
+	return fmt.Sprintf(format, args...)
 }
@@ -0,0 +1,387 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func nested(api *errorsApi, n int) error {
+	if n == 0 {
+		return api.New("nested")
+	}
+	return nested(api, n-1)
+}
+
+func TestApiDepth(t *testing.T) {
+	tests := []struct {
+		depth int
+		want  []string
+	}{
+		{0, []string{"nested"}},
+		{1, []string{"nested"}},
+		{3, []string{"nested", "nested", "nested"}},
+		{pooledDepth + 8, []string{"nested", "nested", "nested", "nested", "nested", "TestApiDepth"}},
+	}
+
+	for _, tt := range tests {
+		api := NewErrorsApi(ApiConfig{CallerSkip: 1, Depth: tt.depth})
+		st := nested(api, 4).(*fundamental).StackTrace()
+		var got []string
+		for _, f := range st {
+			got = append(got, funcname(f.name()))
+			if len(got) == len(tt.want) {
+				break
+			}
+		}
+		assert.Equal(t, tt.want, got, "depth %d", tt.depth)
+		if tt.depth > 0 {
+			assert.LessOrEqual(t, len(st), tt.depth)
+		} else {
+			assert.Len(t, st, DefaultDepth)
+		}
+	}
+}
+
+func TestSkipRedundantStack(t *testing.T) {
+	api := NewErrorsApi(ApiConfig{CallerSkip: 1, SkipRedundantStack: true})
+	root := api.New("root")
+
+	assert.Same(t, root, api.WithStack(root))
+	assert.IsType(t, &withMessage{}, api.Wrap(root, "wrap"))
+	assert.IsType(t, &withMessage{}, api.Wrapf(WithMessage(root, "msg"), "wrap %d", 1))
+	assert.Equal(t, "wrap 1: msg: root", api.Wrapf(WithMessage(root, "msg"), "wrap %d", 1).Error())
+
+	plain := fmt.Errorf("plain")
+	assert.IsType(t, &withStack{}, api.WithStack(plain))
+	assert.IsType(t, &withStack{}, api.Wrap(plain, "wrap"))
+	assert.Nil(t, api.Wrap(nil, "wrap"))
+}
+
+func TestErrorString(t *testing.T) {
+	for _, mode := range []ErrorStringMode{ErrorStringOnDemand, ErrorStringEager, ErrorStringLazy} {
+		api := NewErrorsApi(ApiConfig{CallerSkip: 1, ErrorString: mode})
+		err := api.WithMessage(api.Wrapf(api.WithStack(api.New("root")), "wrap %d", 1), "outer")
+		err = api.Wrap(fmt.Errorf("foreign: %w", err), "top")
+
+		want := "top: foreign: outer: wrap 1: root"
+		assert.Equal(t, want, err.Error(), "mode %d", mode)
+		assert.Equal(t, want, err.Error(), "mode %d", mode)
+		assert.Equal(t, want, fmt.Sprintf("%v", err), "mode %d", mode)
+
+		cached := err.(*withStack).cache != nil
+		assert.Equal(t, mode != ErrorStringOnDemand, cached, "mode %d", mode)
+	}
+}
+
+type countingApi struct {
+	ErrorsApi
+	n int
+}
+
+func (c *countingApi) New(message string) error {
+	c.n++
+	return c.ErrorsApi.New(message)
+}
+
+func TestSetGlobalApi(t *testing.T) {
+	api := &countingApi{ErrorsApi: NewErrorsApi(ApiConfig{CallerSkip: 3})}
+	SetGlobalApi(api)
+	defer SetGlobalApi(nil)
+
+	err := New("counted")
+	assert.Equal(t, 1, api.n)
+	assert.Regexp(t, "^counted\ngithub.com/pkg/errors.TestSetGlobalApi\t.+/github.com/pkg/errors/custom_test.go:\\d+$", fmt.Sprintf("%+v", err))
+
+	// Helpers outside ErrorsApi fall back to the default api.
+	_, err = Wrap1(0, err, "wrapped")
+	assert.EqualError(t, err, "wrapped: counted")
+
+	assert.Equal(t, ErrorsApi(api), GlobalApi())
+
+	SetGlobalApi(nil)
+	New("not counted")
+	assert.Equal(t, 1, api.n)
+
+	custom := NewErrorsApi(ApiConfig{CallerSkip: 2, Depth: 2})
+	SetGlobalApi(custom)
+	assert.Same(t, custom, globalErrorsApi())
+	_, err = Wrap1(0, io.EOF, "wrapped")
+	assert.Len(t, err.(*withStack).StackTrace(), 2)
+}
+
+func TestApiOptions(t *testing.T) {
+	api := NewErrorsApi(ApiConfig{
+		CallerSkip: 1,
+		Options:    []Option{WithMsgSep(" | "), WithStackSep(" <- "), WithFuncSep(" @ ")},
+	})
+	defer SnapshotOptions()()
+	SetOptions(WithMsgSep("#"))
+
+	err := api.Wrap(api.New("inner"), "outer")
+	assert.Regexp(t, "^inner \\| github.com/pkg/errors.TestApiOptions @ .+custom_test.go:\\d+"+
+		" <- outer <- github.com/pkg/errors.TestApiOptions @ .+custom_test.go:\\d+$", fmt.Sprintf("%+v", err))
+	assert.Regexp(t, "^inner \\| github.com/pkg/errors.TestApiOptions @ .+custom_test.go:\\d+$",
+		Cause(err).(Liner).ErrorLineV(VerbosityDebug))
+
+	lines := Lines(api.Wrap(api.New("inner"), "outer"), true)
+	assert.Len(t, lines, 2)
+	assert.Regexp(t, "^outer \\| github.com/pkg/errors.TestApiOptions @ .+custom_test.go:\\d+$", lines[0])
+	assert.Regexp(t, "^inner \\| github.com/pkg/errors.TestApiOptions @ .+custom_test.go:\\d+$", lines[1])
+	lines = LinesWith(api.New("inner"), LineVerbosity(VerbosityDebug), LineSeparators(" / ", " ; ", " at "))
+	assert.Regexp(t, "^inner / github.com/pkg/errors.TestApiOptions at .+custom_test.go:\\d+$", lines[0])
+
+	// Joins separate their errors with the StackSep of their api.
+	err = api.Join(io.EOF, io.ErrUnexpectedEOF)
+	assert.Equal(t, "EOF <- unexpected EOF", fmt.Sprintf("%+v", err))
+	assert.Equal(t, "EOF <- unexpected EOF", fmt.Sprintf("%+v", WithoutStack(err)))
+
+	// Errors of apis without options follow SetOptions.
+	err = NewErrorsApi(ApiConfig{CallerSkip: 1}).New("global")
+	assert.Regexp(t, "^global#github.com/pkg/errors.TestApiOptions\t", fmt.Sprintf("%+v", err))
+}
+
+func TestApiFunctionalOptions(t *testing.T) {
+	api := NewErrorsApi(WithMaxDepth(3), WithSkipRedundantStack(true))
+	assert.Equal(t, ApiConfig{CallerSkip: 1, Depth: 3, SkipRedundantStack: true}, api.cfg)
+	err := nested(api, 5)
+	assert.Len(t, err.(*fundamental).StackTrace(), 3)
+
+	// An ApiConfig replaces the whole configuration and can be adjusted
+	// by the options following it.
+	api = NewErrorsApi(WithMaxDepth(3), ApiConfig{CallerSkip: 2}, WithErrorString(ErrorStringEager))
+	assert.Equal(t, ApiConfig{CallerSkip: 2, ErrorString: ErrorStringEager}, api.cfg)
+}
+
+func TestApiFrameFilter(t *testing.T) {
+	api := NewErrorsApi(WithMaxDepth(2), WithFrameFilter(func(f Frame) bool {
+		return f.name() != "github.com/pkg/errors.nested"
+	}))
+	err := nested(api, 40)
+	matchLines(t, []string{
+		"^nested\ngithub.com/pkg/errors.TestApiFrameFilter\t.+/github.com/pkg/errors/custom_test.go:\\d+\n" +
+			"testing.tRunner\t.+$",
+	}, Lines(err, true))
+
+	api = NewErrorsApi(WithFrameFilter(func(Frame) bool { return false }))
+	assert.Len(t, api.New("none").(*fundamental).StackTrace(), 0)
+}
+
+var derivedApi = NewErrorsApi(WithFormatOptions(WithMsgSep(" | "))).Derive(AddCallerSkip(1))
+
+func failDerived(op string) error {
+	return derivedApi.Errorf("%s failed", op)
+}
+
+func TestDerive(t *testing.T) {
+	err := failDerived("open")
+	assert.Regexp(t, "^open failed \\| github.com/pkg/errors.TestDerive\t.+/github.com/pkg/errors/custom_test.go:\\d+$", fmt.Sprintf("%+v", err))
+
+	child := derivedApi.Derive(WithMaxDepth(4))
+	assert.Equal(t, 2, child.cfg.CallerSkip)
+	assert.Equal(t, 4, child.cfg.Depth)
+	assert.Equal(t, 0, derivedApi.cfg.Depth)
+	assert.Equal(t, " | ", child.format.MsgSep)
+}
+
+func TestApiHelpers(t *testing.T) {
+	api := NewErrorsApi()
+	err := api.NotFoundf("missing")
+	err = api.WithDetails(err, "id", 1)
+	err = api.WithCode(err, CodeInternal)
+	err = api.WithUserMessage(err, "try later")
+	err = api.WithHint(err, "check the id")
+	err = api.WithExitCode(err, 3)
+	assert.EqualError(t, err, "missing")
+	assert.Equal(t, CodeInternal, Code(err))
+	assert.Equal(t, map[string]interface{}{"id": 1}, Details(err))
+	assert.Equal(t, "try later", UserMessage(err))
+	assert.Equal(t, []string{"check the id"}, Hints(err))
+	assert.Equal(t, 3, ExitCode(err))
+	assert.EqualError(t, api.Coalesce(nil, err, io.EOF), "missing\nEOF")
+}
+
+func TestDisableStack(t *testing.T) {
+	api := NewErrorsApi(WithDisableStack(true))
+
+	err := api.New("plain")
+	assert.Len(t, err.(*fundamental).StackTrace(), 0)
+	assert.Equal(t, "plain", fmt.Sprintf("%+v", err))
+
+	assert.Same(t, io.EOF, api.WithStack(io.EOF))
+	err = api.Wrap(io.EOF, "read")
+	assert.EqualError(t, err, "read: EOF")
+	assert.False(t, hasStack(err))
+	assert.Equal(t, []string{"read", "EOF"}, Lines(err, true))
+
+	err = api.WrapFn(io.EOF)
+	assert.EqualError(t, err, "errors.TestDisableStack: EOF")
+	assert.False(t, hasStack(err))
+
+	err = api.NotFoundf("missing %d", 1)
+	assert.False(t, hasStack(err))
+	assert.True(t, IsNotFound(err))
+}
+
+func TestApiTrimPrefix(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	dir := file[:strings.LastIndex(file, "/")+1]
+	api := NewErrorsApi(WithFormatOptions(WithTrimPrefix("/nowhere/", dir)))
+
+	err := api.Wrap(api.New("inner"), "outer")
+	assert.Regexp(t, "^inner\ngithub.com/pkg/errors.TestApiTrimPrefix\tcustom_test.go:\\d+\n"+
+		"outer\ngithub.com/pkg/errors.TestApiTrimPrefix\tcustom_test.go:\\d+$", fmt.Sprintf("%+v", err))
+	assert.Regexp(t, "^inner\ngithub.com/pkg/errors.TestApiTrimPrefix\tcustom_test.go:\\d+$",
+		Cause(err).(Liner).ErrorLineV(VerbosityDebug))
+	assert.Regexp(t, "^outer\ngithub.com/pkg/errors.TestApiTrimPrefix\tcustom_test.go:\\d+$", Lines(err, true)[0])
+
+	b, jerr := StackJSON(err)
+	assert.NoError(t, jerr)
+	assert.Regexp(t, `^\[{"function":"github.com/pkg/errors.TestApiTrimPrefix","file":"custom_test.go","line":\d+}\]$`, string(b))
+	b, jerr = StackJSON(io.EOF)
+	assert.NoError(t, jerr)
+	assert.Equal(t, "null", string(b))
+}
+
+func TestDedupMessages(t *testing.T) {
+	api := NewErrorsApi(WithDedupMessages(true))
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{api.Wrap(context.Canceled, "context canceled"), "context canceled"},
+		{api.WithMessage(api.Wrap(io.EOF, "read"), "read"), "read: EOF"},
+		{api.Wrap(api.Wrap(io.EOF, "read"), "read more"), "read more: read: EOF"},
+		{api.Wrap(io.EOF, "EOF reached"), "EOF reached: EOF"},
+		{NewErrorsApi().Wrap(context.Canceled, "context canceled"), "context canceled: context canceled"},
+	}
+	for _, tt := range tests {
+		assert.EqualError(t, tt.err, tt.want)
+	}
+
+	err := api.Wrap(context.Canceled, "context canceled")
+	assert.True(t, hasStack(err))
+	assert.Len(t, Lines(err, false), 1)
+}
+
+func TestLocationPrefix(t *testing.T) {
+	api := NewErrorsApi(WithLocationPrefix(true))
+	_, _, line, _ := runtime.Caller(0)
+	err := api.Wrap(io.EOF, "read")
+	assert.EqualError(t, err, fmt.Sprintf("custom_test.go:%d: read: EOF", line+1))
+	assert.True(t, hasStack(err))
+
+	err = api.Wrapf(err, "load %s", "config")
+	assert.EqualError(t, err, fmt.Sprintf("custom_test.go:%d: load config: custom_test.go:%d: read: EOF", line+5, line+1))
+
+	for _, api := range []*errorsApi{
+		NewErrorsApi(WithLocationPrefix(true), WithDisableStack(true)),
+		NewErrorsApi(WithLocationPrefix(true), WithSkipRedundantStack(true)),
+	} {
+		_, _, line, _ := runtime.Caller(0)
+		err := api.Wrap(New("foo"), "")
+		assert.EqualError(t, err, fmt.Sprintf("custom_test.go:%d: foo", line+1))
+		assert.IsType(t, &withMessage{}, err)
+	}
+}
+
+func TestStackRules(t *testing.T) {
+	api := NewErrorsApi(WithStackRules(
+		StackRule{Package: "github.com/pkg", DisableStack: true},
+		StackRule{Package: "github.com/pkg/errors/...", Depth: 1},
+		StackRule{Package: "github.com/pkg/errors/errhttp", DisableStack: true},
+	))
+	err := api.New("foo")
+	assert.Len(t, nearestStack(err), 1)
+	assert.Len(t, nearestStack(api.Wrap(io.EOF, "read")), 1)
+
+	api = api.Derive(WithStackRules(StackRule{Package: "github.com/pkg/errors", DisableStack: true}))
+	assert.False(t, hasStack(api.New("foo")))
+	assert.False(t, hasStack(api.WithCaller(io.EOF)))
+
+	api = NewErrorsApi(WithMaxDepth(1), WithStackRules(StackRule{Package: "example.com/app"}))
+	assert.Len(t, nearestStack(api.New("foo")), 1)
+}
+
+func TestPkgPath(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"main.main", "main"},
+		{"github.com/pkg/errors.(*withStack).Format", "github.com/pkg/errors"},
+		{"gopkg.in/yaml%2ev3.Unmarshal", "gopkg.in/yaml.v3"},
+		{"example.com/app/internal/cache.Get.func1", "example.com/app/internal/cache"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, pkgPath(tt.name), tt.name)
+	}
+}
+
+func TestMaxErrorLength(t *testing.T) {
+	api := NewErrorsApi(WithMaxErrorLength(16))
+	body := strings.Repeat("x", 1<<20)
+	err := api.Errorf("bad response: %s", body)
+	assert.EqualError(t, err, "bad response: xx... (1048574 more bytes)")
+	assert.Contains(t, fmt.Sprintf("%+v", err), body)
+
+	err = api.Wrap(io.EOF, "read body")
+	assert.EqualError(t, err, "read body: EOF")
+	err = api.WithMessage(err, "fetch https://example.com")
+	assert.EqualError(t, err, "fetch https://ex... (25 more bytes)")
+	assert.EqualError(t, api.WithMessage(err, "é"), "é: fetch https:... (29 more bytes)")
+
+	err = api.Wrapf(io.EOF, "read %s", body)
+	assert.Equal(t, err.Error(), fmt.Sprintf("%s", err))
+	assert.Equal(t, err.Error(), fmt.Sprintf("%v", err))
+	err = api.WithStack(api.WithMessage(io.EOF, body))
+	assert.Equal(t, "xxxxxxxxxxxxxxxx... (1048565 more bytes)", fmt.Sprintf("%s", err))
+	assert.Equal(t, err.Error(), fmt.Sprintf("%v", err))
+	assert.EqualError(t, NewErrorsApi(WithMaxErrorLength(3)).New("éé"), "é... (2 more bytes)")
+
+	assert.Len(t, NewErrorsApi().Errorf("%s", body).Error(), len(body))
+}
+
+func TestTranslator(t *testing.T) {
+	fr := NewErrorsApi(WithTranslator(func(msg string) string {
+		return map[string]string{"read": "lecture", "not found": "introuvable"}[msg]
+	}))
+	tag := NewErrorsApi(WithTranslator(func(msg string) string { return msg + " [INC-42]" }))
+
+	err := fr.New("not found")
+	assert.EqualError(t, err, "introuvable")
+	assert.Equal(t, "introuvable", fmt.Sprintf("%v", err))
+
+	err = tag.Wrap(fr.Wrap(err, "read"), "load")
+	assert.EqualError(t, err, "load [INC-42]: lecture: introuvable")
+	assert.Equal(t, "load [INC-42]: lecture: introuvable", fmt.Sprint(err))
+	assert.Equal(t, []string{"load [INC-42]", "lecture", "introuvable"}, Messages(err))
+	assert.Regexp(t, "^introuvable\n.+\nlecture\n.+\nload \\[INC-42\\]\n", fmt.Sprintf("%+v", err))
+
+	err = NewErrorsApi(WithTranslator(strings.ToUpper), WithMaxErrorLength(8)).WithMessage(io.EOF, "unexpected")
+	assert.EqualError(t, err, "UNEXPECT... (7 more bytes)")
+	assert.Equal(t, "UNEXPECT... (7 more bytes)", fmt.Sprintf("%s", err))
+}
+
+func TestWrapNilAsNew(t *testing.T) {
+	api := NewErrorsApi(WithWrapNilAsNew(true))
+	for _, err := range []error{
+		api.Wrap(nil, "read config"),
+		api.Wrapf(nil, "read %s", "config"),
+		api.WrapIf(true, nil, "read config"),
+		api.WrapfIf(true, nil, "read %s", "config"),
+		NewErrorsApi().WrapOrNew(nil, "read config"),
+	} {
+		assert.EqualError(t, err, "read config")
+		assert.Len(t, nearestStack(err), 1)
+		assert.Equal(t, "TestWrapNilAsNew", nearestStack(err)[0].name()[len("github.com/pkg/errors."):])
+	}
+	assert.Nil(t, api.WrapIf(false, nil, "read config"))
+	assert.Nil(t, NewErrorsApi().Wrap(nil, "read config"))
+	assert.EqualError(t, NewErrorsApi().WrapOrNew(io.EOF, "read config"), "read config: EOF")
+	assert.Regexp(t, "^read config\ngithub.com/pkg/errors.TestWrapNilAsNew\t.+/custom_test.go:\\d+", fmt.Sprintf("%+v", WrapOrNew(nil, "read config")))
+}
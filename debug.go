@@ -0,0 +1,68 @@
+package errors
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// DebugEnv is the environment variable that enables the debug annotations
+// of WithStackIfDebug and WrapDebugf when it is set to a non-empty value at
+// startup.
+const DebugEnv = "ERRORS_DEBUG"
+
+var debug int32
+
+func init() {
+	if os.Getenv(DebugEnv) != "" {
+		debug = 1
+	}
+}
+
+// SetDebug enables or disables the debug annotations of WithStackIfDebug
+// and WrapDebugf, overriding DebugEnv.
+func SetDebug(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&debug, v)
+}
+
+// Debug reports whether the debug annotations are enabled.
+func Debug() bool {
+	return atomic.LoadInt32(&debug) != 0
+}
+
+// WithStackIfDebug annotates err with a stack trace at the point it was
+// called, as WithStack does, if the debug annotations are enabled, and
+// otherwise returns err as is, so that diagnostics can be left in code
+// paths where they would cost too much in production.
+func WithStackIfDebug(err error) error {
+	return globalErrorsApi().WithStackIfDebug(err)
+}
+
+// WrapDebugf annotates err as Wrapf does if the debug annotations are
+// enabled, and otherwise returns err as is.
+func WrapDebugf(err error, format string, args ...interface{}) error {
+	return globalErrorsApi().WrapDebugf(err, format, args...)
+}
+
+func (e *errorsApi) WithStackIfDebug(err error) error {
+	if err == nil || !Debug() {
+		return err
+	}
+	if e.cfg.SkipRedundantStack && hasStack(err) {
+		return err
+	}
+	if c, ok := e.collapse(err); ok {
+		return c
+	}
+	return e.withStack(err, "", nil, e.callers(0))
+}
+
+func (e *errorsApi) WrapDebugf(err error, format string, args ...interface{}) error {
+	if err == nil || !Debug() {
+		return err
+	}
+	return e.wrapf(1, err, format, args)
+}
@@ -0,0 +1,28 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugAnnotations(t *testing.T) {
+	defer SetDebug(Debug())
+
+	SetDebug(false)
+	assert.Same(t, io.EOF, WithStackIfDebug(io.EOF))
+	assert.Same(t, io.EOF, WrapDebugf(io.EOF, "read %s", "config"))
+	assert.Nil(t, WithStackIfDebug(nil))
+
+	SetDebug(true)
+	assert.True(t, Debug())
+	assert.Nil(t, WrapDebugf(nil, "read"))
+	err := WithStackIfDebug(io.EOF)
+	assert.EqualError(t, err, "EOF")
+	assert.Regexp(t, "^EOF\ngithub.com/pkg/errors.TestDebugAnnotations\t.+/github.com/pkg/errors/debug_test.go:22$", fmt.Sprintf("%+v", err))
+	err = WrapDebugf(io.EOF, "read %s", "config")
+	assert.EqualError(t, err, "read config: EOF")
+	assert.Regexp(t, "^EOF\nread config\ngithub.com/pkg/errors.TestDebugAnnotations\t.+/github.com/pkg/errors/debug_test.go:25$", fmt.Sprintf("%+v", err))
+}
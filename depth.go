@@ -0,0 +1,64 @@
+package errors
+
+// CollapsedWrapsKey is the detail key under which an api with a maximum
+// chain depth counts the wraps it did not add to a chain; see
+// WithMaxChainDepth.
+const CollapsedWrapsKey = "errors.collapsed_wraps"
+
+// WithMaxChainDepth sets ApiConfig.MaxChainDepth.
+func WithMaxChainDepth(depth int) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.MaxChainDepth = depth
+	})
+}
+
+// CollapsedWraps returns the number of wraps that were not added to err's
+// chain because it had reached the maximum chain depth of the api.
+func CollapsedWraps(err error) int {
+	n, _ := Details(err)[CollapsedWrapsKey].(int)
+	return n
+}
+
+// collapse returns err with one more wrap counted under CollapsedWrapsKey,
+// and true, if err's chain is at least MaxChainDepth layers deep. The count
+// is kept in a single layer, which is replaced as it grows, with copies of
+// the layers added above it since, such as codes and details.
+func (e *errorsApi) collapse(err error) (error, bool) {
+	max := e.cfg.MaxChainDepth
+	if max <= 0 {
+		return nil, false
+	}
+	depth, copyable := 0, true
+	for c := err; c != nil; c = unwrapOnce(c) {
+		if d, ok := c.(*withDetails); ok && copyable && isCollapsed(d) {
+			return recount(err, d), true
+		}
+		if _, ok := c.(rewrapper); !ok {
+			// The layers above a count found beneath c could not be
+			// copied, so there is no need to look for one.
+			copyable = false
+		}
+		if depth++; !copyable && depth >= max {
+			break
+		}
+	}
+	if depth < max {
+		return nil, false
+	}
+	return &withDetails{annotation{err}, []detail{{CollapsedWrapsKey, 1}}}, true
+}
+
+// isCollapsed reports whether d is the layer collapse counts wraps in.
+func isCollapsed(d *withDetails) bool {
+	return len(d.details) == 1 && d.details[0].key == CollapsedWrapsKey
+}
+
+// recount returns a copy of err's chain down to d, the layer counting the
+// collapsed wraps, with one more wrap counted in d.
+func recount(err error, d *withDetails) error {
+	if w, ok := err.(*withDetails); ok && w == d {
+		n, _ := d.details[0].value.(int)
+		return &withDetails{d.annotation, []detail{{CollapsedWrapsKey, n + 1}}}
+	}
+	return err.(rewrapper).rewrap(recount(unwrapOnce(err), d))
+}
@@ -0,0 +1,68 @@
+package errors
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxChainDepth(t *testing.T) {
+	api := NewErrorsApi(WithMaxChainDepth(4))
+	err := api.Wrap(io.EOF, "read")
+	err = api.WithMessage(err, "load")
+	assert.Equal(t, 0, CollapsedWraps(err))
+
+	for i := 0; i < 500; i++ {
+		err = api.Wrapf(err, "attempt %d", i)
+	}
+	assert.EqualError(t, err, "attempt 0: load: read: EOF")
+	assert.Equal(t, 499, CollapsedWraps(err))
+	assert.Len(t, Chain(err), 5)
+	assert.ErrorIs(t, err, io.EOF)
+
+	err = api.WrapPlain(err, "again")
+	assert.Equal(t, 500, CollapsedWraps(err))
+	assert.Len(t, Chain(err), 5)
+
+	err = NewErrorsApi().Wrap(io.EOF, "read")
+	for i := 0; i < 10; i++ {
+		err = NewErrorsApi().Wrap(err, "retry")
+	}
+	assert.Equal(t, 0, CollapsedWraps(err))
+}
+
+func TestMaxChainDepthInterleaved(t *testing.T) {
+	api := NewErrorsApi(WithMaxChainDepth(3))
+	err := api.Wrap(api.Wrap(io.EOF, "read"), "load")
+	err = api.Wrap(err, "retry")
+	assert.Equal(t, 1, CollapsedWraps(err))
+
+	err = WithCode(err, CodeUnavailable)
+	depth := len(Chain(err))
+	for i := 0; i < 10; i++ {
+		err = api.Wrap(err, "retry")
+		err = api.WithStack(err)
+		err = api.WithLazyMessage(err, func() string { return "lazy" })
+	}
+	assert.Equal(t, 31, CollapsedWraps(err))
+	assert.Len(t, Chain(err), depth)
+	assert.Equal(t, CodeUnavailable, Code(err))
+	assert.ErrorIs(t, err, io.EOF)
+
+	func() {
+		defer func() {
+			err = recover().(error)
+		}()
+		api.MustNil(err)
+	}()
+	assert.Equal(t, 32, CollapsedWraps(err))
+	assert.Len(t, Chain(err), depth)
+
+	func() {
+		defer Handle(&err, nil)
+		api.Check(err)
+	}()
+	assert.Equal(t, 33, CollapsedWraps(err))
+	assert.Len(t, Chain(err), depth)
+}
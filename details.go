@@ -0,0 +1,204 @@
+package errors
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// WithDetails annotates err with key-value pairs that describe it, such as
+// the identifiers of the objects involved, for logging and inspection by
+// Details. keysAndValues alternates keys and values; keys that are not
+// strings are formatted with fmt.Sprint, and a trailing key without a value
+// gets a nil value. Values that are expensive to compute can be given as a
+// *LazyValue, or as a func() interface{}, which is wrapped in one; they are
+// only computed when the details are read, formatted or exported.
+// If err is nil, WithDetails returns nil.
+func WithDetails(err error, keysAndValues ...interface{}) error {
+	return globalErrorsApi().WithDetails(err, keysAndValues...)
+}
+
+// Details returns the key-value pairs attached to err's chain by
+// WithDetails. When a key is attached more than once, the value closest to
+// the outermost error wins. Details returns nil if there are none.
+func Details(err error) map[string]interface{} {
+	var details map[string]interface{}
+	for ; err != nil; err = unwrapOnce(err) {
+		d, ok := err.(*withDetails)
+		if !ok {
+			continue
+		}
+		if details == nil {
+			details = make(map[string]interface{}, len(d.details))
+		}
+		for _, kv := range d.details {
+			if _, ok := details[kv.key]; !ok {
+				details[kv.key] = kv.get()
+			}
+		}
+	}
+	return details
+}
+
+// DetailsOfType returns every value of type T attached to err's chain by
+// WithDetails, whatever its key, outermost first. Values attached more than
+// once, even under the same key, are all returned.
+func DetailsOfType[T any](err error) []T {
+	var values []T
+	for ; err != nil; err = unwrapOnce(err) {
+		d, ok := err.(*withDetails)
+		if !ok {
+			continue
+		}
+		for _, kv := range d.details {
+			if v, ok := kv.get().(T); ok {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+type detail struct {
+	key   string
+	value interface{}
+}
+
+// get returns the value of d, computing it if it is lazy.
+func (d detail) get() interface{} {
+	if l, ok := d.value.(*LazyValue); ok {
+		return l.Value()
+	}
+	return d.value
+}
+
+// LazyValue is a detail value that is computed the first time it is
+// needed, so that errors that are handled and discarded do not pay for it.
+type LazyValue struct {
+	once  sync.Once
+	fn    func() interface{}
+	value interface{}
+}
+
+// Lazy returns a detail value computed by fn when it is first needed.
+func Lazy(fn func() interface{}) *LazyValue {
+	return &LazyValue{fn: fn}
+}
+
+// Value returns the value computed by the function of l, calling it on
+// the first call only.
+func (l *LazyValue) Value() interface{} {
+	l.once.Do(func() {
+		l.value = l.fn()
+		l.fn = nil
+	})
+	return l.value
+}
+
+type withDetails struct {
+	annotation
+	details []detail
+}
+
+// Format formats w as its cause does, except that %+v also prints the
+// payloads attached by WithPayload, which would otherwise only show in
+// Details.
+func (w *withDetails) Format(s fmt.State, verb rune) {
+	w.annotation.Format(s, verb)
+	if verb == 'v' && s.Flag('+') && !s.Flag('-') {
+		formatPayloads(s, w.details)
+	}
+}
+
+func (w *withDetails) rewrap(cause error) error {
+	c := *w
+	c.cause = cause
+	return &c
+}
+
+func (e *errorsApi) WithDetails(err error, keysAndValues ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return &withDetails{annotation{err}, makeDetails(keysAndValues)}
+}
+
+// makeDetails pairs keysAndValues as WithDetails does.
+func makeDetails(keysAndValues []interface{}) []detail {
+	details := make([]detail, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		d := detail{}
+		if key, ok := keysAndValues[i].(string); ok {
+			d.key = key
+		} else {
+			d.key = fmt.Sprint(keysAndValues[i])
+		}
+		if i+1 < len(keysAndValues) {
+			d.value = keysAndValues[i+1]
+			if fn, ok := d.value.(func() interface{}); ok {
+				d.value = Lazy(fn)
+			}
+		}
+		details = append(details, d)
+	}
+	return details
+}
+
+// defaultDetails holds the []detail set by SetDefaultDetails.
+var defaultDetails atomic.Value
+
+// SetDefaultDetails sets key-value pairs, such as the name, version and
+// region of the service, that are attached as by WithDetails to every error
+// created by the global api: those of New, Errorf, NewPlain and the other
+// constructors of new errors, so that their exports carry the context of
+// the deployment they occurred in. Wrapping an error does not attach them.
+// Calling SetDefaultDetails without arguments removes them.
+func SetDefaultDetails(keysAndValues ...interface{}) {
+	defaultDetails.Store(makeDetails(keysAndValues))
+}
+
+// withDefaultDetails annotates err, created by e, with the details set by
+// SetDefaultDetails if e is the global api.
+func (e *errorsApi) withDefaultDetails(err error) error {
+	details, _ := defaultDetails.Load().([]detail)
+	if len(details) == 0 || e != globalErrorsApi() {
+		return err
+	}
+	return &withDetails{annotation{err}, details}
+}
+
+// RequestIDKey is the detail key WithRequestID stores request IDs under.
+const RequestIDKey = "errors.request_id"
+
+// WithRequestID annotates err with the ID of the request it occurred in,
+// as the detail RequestIDKey, so that it can be correlated with the logs
+// and traces of that request.
+// If err is nil, WithRequestID returns nil.
+func WithRequestID(err error, id string) error {
+	return globalErrorsApi().WithRequestID(err, id)
+}
+
+// RequestID returns the request ID attached to err's chain by
+// WithRequestID, and whether there was one. If several were attached, the
+// outermost wins.
+func RequestID(err error) (string, bool) {
+	for ; err != nil; err = unwrapOnce(err) {
+		d, ok := err.(*withDetails)
+		if !ok {
+			continue
+		}
+		for _, kv := range d.details {
+			if kv.key != RequestIDKey {
+				continue
+			}
+			if id, ok := kv.get().(string); ok {
+				return id, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (e *errorsApi) WithRequestID(err error, id string) error {
+	return e.WithDetails(err, RequestIDKey, id)
+}
@@ -0,0 +1,112 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetails(t *testing.T) {
+	assert.Nil(t, WithDetails(nil, "k", "v"))
+	assert.Nil(t, Details(io.EOF))
+
+	err := WithDetails(io.EOF, "user", "alice", "attempt", 2)
+	err = Wrap(err, "read")
+	err = WithDetails(err, "user", "bob", 7, "seven", "dangling")
+	assert.EqualError(t, err, "read: EOF")
+	assert.Equal(t, map[string]interface{}{
+		"user":     "bob",
+		"attempt":  2,
+		"7":        "seven",
+		"dangling": nil,
+	}, Details(err))
+	assert.Same(t, io.EOF, Cause(err))
+	assert.Equal(t, "read: EOF", fmt.Sprintf("%v", err))
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Nil(t, WithRequestID(nil, "req-1"))
+	_, ok := RequestID(io.EOF)
+	assert.False(t, ok)
+
+	err := WithRequestID(io.EOF, "req-1")
+	err = WithDetails(Wrap(err, "read"), "user", "alice")
+	id, ok := RequestID(err)
+	assert.True(t, ok)
+	assert.Equal(t, "req-1", id)
+	assert.Equal(t, map[string]interface{}{"user": "alice", RequestIDKey: "req-1"}, Details(err))
+
+	id, _ = RequestID(WithRequestID(err, "req-2"))
+	assert.Equal(t, "req-2", id)
+
+	called := false
+	lazy := Lazy(func() interface{} { called = true; return "alice" })
+	id, _ = RequestID(WithDetails(err, "user", lazy))
+	assert.Equal(t, "req-1", id)
+	assert.False(t, called)
+}
+
+type fieldViolation struct {
+	Field, Reason string
+}
+
+func TestDetailsOfType(t *testing.T) {
+	assert.Nil(t, DetailsOfType[fieldViolation](io.EOF))
+
+	err := WithDetails(io.EOF, "name", fieldViolation{"name", "empty"}, "age", 3)
+	err = Wrap(err, "validate")
+	err = WithDetails(err, "email", fieldViolation{"email", "invalid"}, "name", fieldViolation{"name", "too long"})
+	assert.Equal(t, []fieldViolation{
+		{"email", "invalid"},
+		{"name", "too long"},
+		{"name", "empty"},
+	}, DetailsOfType[fieldViolation](err))
+	assert.Equal(t, []int{3}, DetailsOfType[int](err))
+	assert.Len(t, DetailsOfType[fmt.Stringer](err), 0)
+}
+
+func TestLazyDetails(t *testing.T) {
+	calls := 0
+	plan := func() interface{} {
+		calls++
+		return "seq scan"
+	}
+	err := WithDetails(io.EOF, "plan", plan, "size", Lazy(func() interface{} { return 42 }), "id", "a")
+	err = WithRequestID(WithDetails(err, "user", Lazy(func() interface{} { return "bob" })), "req-1")
+	assert.Equal(t, 0, calls)
+
+	assert.Equal(t, map[string]interface{}{
+		RequestIDKey: "req-1",
+		"user":       "bob",
+		"plan":       "seq scan",
+		"size":       42,
+		"id":         "a",
+	}, Details(err))
+	assert.Equal(t, []int{42}, DetailsOfType[int](err))
+	assert.Equal(t, "seq scan", Tree(err).Children[0].Children[0].Details["plan"])
+	assert.Equal(t, 1, calls)
+
+	other := WithDetails(io.EOF, "plan", "seq scan", "size", 42, "id", "a")
+	assert.True(t, Equal(Unwrap(Unwrap(err)), other))
+}
+
+func TestSetDefaultDetails(t *testing.T) {
+	SetDefaultDetails("service", "billing", "version", "1.4.2")
+	defer SetDefaultDetails()
+
+	want := map[string]interface{}{"service": "billing", "version": "1.4.2"}
+	assert.Equal(t, want, Details(New("foo")))
+	assert.Equal(t, want, Details(NotFoundf("user %d", 1)))
+	assert.True(t, IsNotFound(NotFoundf("user %d", 1)))
+	assert.EqualError(t, Errorf("foo %d", 1), "foo 1")
+	assert.Nil(t, Details(Wrap(io.EOF, "read")))
+	assert.Nil(t, Details(NewErrorsApi().New("foo")))
+
+	err := WithDetails(New("foo"), "version", "2.0.0")
+	assert.Equal(t, "2.0.0", Details(err)["version"])
+
+	SetDefaultDetails()
+	assert.Nil(t, Details(New("foo")))
+}
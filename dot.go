@@ -0,0 +1,57 @@
+package errors
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+// DOT returns the tree of err, as returned by Tree, as a Graphviz graph in
+// the DOT language, for visualizing failures that join many errors:
+//
+//	errors.DOT(err) | dot -Tsvg > err.svg
+//
+// There is one node per layer, labelled with the message of the layer, or
+// with what it carries if it has none, such as "[code=NotFound]". Layers
+// that captured a stack trace are drawn bold, with the top frame of the
+// stack in their label. Joined errors are drawn as diamonds.
+func DOT(err error) string {
+	var b strings.Builder
+	b.WriteString("digraph errors {\n\tnode [shape=box];\n")
+	if root := Tree(err); root != nil {
+		id := 0
+		writeDOTNode(&b, root, &id)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// writeDOTNode writes n and its descendants to b, numbering them from *id,
+// and returns the ID of n.
+func writeDOTNode(b *strings.Builder, n *Node, id *int) string {
+	name := "n" + strconv.Itoa(*id)
+	*id++
+
+	label := n.Message
+	if label == "" {
+		if _, ok := n.Err.(interface{ Unwrap() []error }); ok {
+			label = "join"
+		} else {
+			label = "[" + describeLayer(n.Err) + "]"
+		}
+	}
+	var attrs string
+	if _, ok := n.Err.(interface{ Unwrap() []error }); ok {
+		attrs = ", shape=diamond"
+	}
+	if len(n.Stack) > 0 {
+		f := n.Stack[0]
+		label += "\n" + funcname(f.name()) + " " + path.Base(f.file()) + ":" + strconv.Itoa(f.line())
+		attrs += ", style=bold"
+	}
+	b.WriteString("\t" + name + " [label=" + strconv.Quote(label) + attrs + "];\n")
+	for _, child := range n.Children {
+		b.WriteString("\t" + name + " -> " + writeDOTNode(b, child, id) + ";\n")
+	}
+	return name
+}
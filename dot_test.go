@@ -0,0 +1,27 @@
+package errors
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDOT(t *testing.T) {
+	assert.Equal(t, "digraph errors {\n\tnode [shape=box];\n}\n", DOT(nil))
+
+	err := WithCode(Join(WithMessage(io.EOF, "read \"a\""), New("b")), CodeInternal)
+	assert.Regexp(t, `^digraph errors {
+	node \[shape=box\];
+	n0 \[label="\[code=Internal\]"\];
+	n1 \[label="join", shape=diamond\];
+	n2 \[label="read \\"a\\""\];
+	n3 \[label="EOF"\];
+	n2 -> n3;
+	n1 -> n2;
+	n4 \[label="b\\nTestDOT dot_test.go:\d+", style=bold\];
+	n1 -> n4;
+	n0 -> n1;
+}
+$`, DOT(err))
+}
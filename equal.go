@@ -0,0 +1,73 @@
+package errors
+
+import "reflect"
+
+// Equal reports whether a and b are structurally equal: whether their
+// chains have layers of the same types, with the same messages, codes,
+// details and other annotations, in the same order. Stack traces and the
+// times attached by WithTime are ignored, so errors created by the same
+// code at different places or times are equal. It is meant for tests and
+// for deduplicating errors, where == compares too much and Is too little.
+func Equal(a, b error) bool {
+	for a != nil && b != nil {
+		if reflect.TypeOf(a) != reflect.TypeOf(b) || ownMessage(a) != ownMessage(b) || !equalAnnotations(a, b) {
+			return false
+		}
+		if ma, ok := a.(interface{ Unwrap() []error }); ok {
+			ea, eb := ma.Unwrap(), b.(interface{ Unwrap() []error }).Unwrap()
+			if len(ea) != len(eb) {
+				return false
+			}
+			for i := range ea {
+				if !Equal(ea[i], eb[i]) {
+					return false
+				}
+			}
+			return true
+		}
+		a, b = unwrapOnce(a), unwrapOnce(b)
+	}
+	return a == nil && b == nil
+}
+
+// equalAnnotations reports whether a and b, which have the same type,
+// carry the same annotation.
+func equalAnnotations(a, b error) bool {
+	switch a := a.(type) {
+	case *withCode:
+		return a.code == b.(*withCode).code
+	case *withUserMessage:
+		return a.msg == b.(*withUserMessage).msg
+	case *withHint:
+		return a.hint == b.(*withHint).hint
+	case *withExitCode:
+		return a.code == b.(*withExitCode).code
+	case *withResource:
+		return a.resource == b.(*withResource).resource
+	case *withRetryable:
+		return a.retryable == b.(*withRetryable).retryable
+	case *withRetryAfter:
+		return a.delay == b.(*withRetryAfter).delay
+	case *withStopped:
+		return a.stopped.Error() == b.(*withStopped).stopped.Error()
+	case *PanicError:
+		return reflect.DeepEqual(a.value, b.(*PanicError).value)
+	case *withDetails:
+		return equalDetails(a.details, b.(*withDetails).details)
+	}
+	return true
+}
+
+// equalDetails reports whether a and b have the same keys and values, in
+// the same order, computing lazy values.
+func equalDetails(a, b []detail) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].key != b[i].key || !reflect.DeepEqual(a[i].get(), b[i].get()) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeEqualError(code ErrorCode, id int) error {
+	err := Wrap(WithDetails(New("inner"), "id", id), "outer")
+	return WithTime(WithCode(err, code))
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b error
+		want bool
+	}{
+		{nil, nil, true},
+		{io.EOF, nil, false},
+		{io.EOF, io.EOF, true},
+		{New("foo"), New("foo"), true},
+		{New("foo"), New("bar"), false},
+		{New("foo"), NewPlain("foo"), true},
+		{New("foo"), fmt.Errorf("foo"), false},
+		{Wrap(io.EOF, "foo"), Wrap(io.EOF, "foo"), true},
+		{Wrap(io.EOF, "foo"), WithMessage(io.EOF, "foo"), false},
+		{Wrap(io.EOF, "foo"), Wrap(io.ErrUnexpectedEOF, "foo"), false},
+		{Wrap(io.EOF, "foo"), Wrap(Wrap(io.EOF, "foo"), "foo"), false},
+		{makeEqualError(CodeInternal, 1), makeEqualError(CodeInternal, 1), true},
+		{makeEqualError(CodeInternal, 1), makeEqualError(CodeNotFound, 1), false},
+		{makeEqualError(CodeInternal, 1), makeEqualError(CodeInternal, 2), false},
+		{WithHint(io.EOF, "a"), WithHint(io.EOF, "b"), false},
+		{WithUserMessage(io.EOF, "a"), WithUserMessage(io.EOF, "a"), true},
+		{WithExitCode(io.EOF, 2), WithExitCode(io.EOF, 3), false},
+		{Join(New("a"), io.EOF), Join(New("a"), io.EOF), true},
+		{Join(New("a"), io.EOF), Join(New("a")), false},
+		{Join(New("a"), io.EOF), Join(New("b"), io.EOF), false},
+	}
+	for i, tt := range tests {
+		assert.Equal(t, tt.want, Equal(tt.a, tt.b), "test %d: Equal(%v, %v)", i, tt.a, tt.b)
+	}
+}
+
+func TestEqualIgnoresTime(t *testing.T) {
+	now := time.Now()
+	a := NewErrorsApi(WithClock(func() time.Time { return now })).WithTime(io.EOF)
+	b := NewErrorsApi(WithClock(func() time.Time { return now.Add(time.Hour) })).WithTime(io.EOF)
+	assert.True(t, Equal(a, b))
+}
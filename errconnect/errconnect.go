@@ -0,0 +1,156 @@
+// Package errconnect converts between the errors of github.com/pkg/errors
+// and the *connect.Error of connectrpc.com/connect.
+package errconnect
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ToConnect returns err as a *connect.Error for returning from a connect
+// handler. The code is that of errors.Code(err); errors without a code are
+// reported as connect.CodeUnknown. The details attached by
+// errors.WithDetails are sent as a google.protobuf.Struct detail, with
+// values that do not map to JSON formatted by fmt.Sprint. err is wrapped,
+// so its message is kept and it can still be inspected on the server.
+// If err already has a *connect.Error in its chain, that one is returned.
+// If err is nil, ToConnect returns nil.
+func ToConnect(err error) *connect.Error {
+	if err == nil {
+		return nil
+	}
+	var ce *connect.Error
+	if errors.As(err, &ce) {
+		return ce
+	}
+	code := connect.CodeUnknown
+	if c := errors.Code(err); c != errors.CodeOK && c <= errors.CodeUnauthenticated {
+		code = connect.Code(c)
+	}
+	ce = connect.NewError(code, err)
+	if details := errors.Details(err); len(details) > 0 {
+		if detail, err := connect.NewErrorDetail(detailsStruct(details)); err == nil {
+			ce.AddDetail(detail)
+		}
+	}
+	return ce
+}
+
+// detailsStruct converts details to a Struct.
+func detailsStruct(details map[string]interface{}) *structpb.Struct {
+	s := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(details))}
+	for k, v := range details {
+		value, err := structpb.NewValue(v)
+		if err != nil {
+			value = structpb.NewStringValue(fmt.Sprint(v))
+		}
+		s.Fields[k] = value
+	}
+	return s
+}
+
+// FromConnect returns ce as an error of this package, for callers of
+// connect clients: its message is the message of ce without the code
+// prefix, its errors.Code is the code of ce, and the fields of the
+// google.protobuf.Struct details of ce are attached as errors.WithDetails
+// details, so errors converted by ToConnect round-trip. ce stays in the
+// chain, for errors.As.
+// If ce is nil, FromConnect returns nil.
+func FromConnect(ce *connect.Error) error {
+	if ce == nil {
+		return nil
+	}
+	var err error = &remoteError{ce}
+	for _, detail := range ce.Details() {
+		value, derr := detail.Value()
+		s, ok := value.(*structpb.Struct)
+		if derr != nil || !ok {
+			continue
+		}
+		kv := make([]interface{}, 0, 2*len(s.Fields))
+		for k, v := range s.Fields {
+			kv = append(kv, k, v.AsInterface())
+		}
+		err = errors.WithDetails(err, kv...)
+	}
+	return errors.WithCode(err, errors.ErrorCode(ce.Code()))
+}
+
+// remoteError gives a *connect.Error the message it was created with.
+type remoteError struct {
+	ce *connect.Error
+}
+
+func (e *remoteError) Error() string { return e.ce.Message() }
+func (e *remoteError) Unwrap() error { return e.ce }
+
+// NewInterceptor returns an interceptor that converts the errors returned
+// by handlers with ToConnect, and the errors received by clients with
+// FromConnect, for unary and streaming procedures alike.
+func NewInterceptor() connect.Interceptor {
+	return interceptor{}
+}
+
+type interceptor struct{}
+
+func (interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		res, err := next(ctx, req)
+		if err == nil {
+			return res, nil
+		}
+		if req.Spec().IsClient {
+			return res, fromError(err)
+		}
+		return res, ToConnect(err)
+	}
+}
+
+func (interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		return streamingClientConn{next(ctx, spec)}
+	}
+}
+
+func (interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := next(ctx, conn); err != nil {
+			return ToConnect(err)
+		}
+		return nil
+	}
+}
+
+// streamingClientConn converts the errors a client stream receives with
+// FromConnect. io.EOF, which ends streams, is passed through as it is.
+type streamingClientConn struct {
+	connect.StreamingClientConn
+}
+
+func (c streamingClientConn) Send(msg interface{}) error {
+	return fromError(c.StreamingClientConn.Send(msg))
+}
+
+func (c streamingClientConn) CloseRequest() error {
+	return fromError(c.StreamingClientConn.CloseRequest())
+}
+
+func (c streamingClientConn) Receive(msg interface{}) error {
+	return fromError(c.StreamingClientConn.Receive(msg))
+}
+
+func (c streamingClientConn) CloseResponse() error {
+	return fromError(c.StreamingClientConn.CloseResponse())
+}
+
+// fromError converts the *connect.Error err is, if it is one.
+func fromError(err error) error {
+	if ce, ok := err.(*connect.Error); ok {
+		return FromConnect(ce)
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package errconnect
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestToConnect(t *testing.T) {
+	assert.Nil(t, ToConnect(nil))
+
+	ce := ToConnect(io.EOF)
+	assert.Equal(t, connect.CodeUnknown, ce.Code())
+	assert.Equal(t, "EOF", ce.Message())
+	assert.Empty(t, ce.Details())
+
+	err := errors.WithDetails(errors.WithCode(errors.Wrap(io.EOF, "read"), errors.CodeNotFound), "id", "42", "ch", make(chan int))
+	ce = ToConnect(err)
+	assert.Equal(t, connect.CodeNotFound, ce.Code())
+	assert.Equal(t, "read: EOF", ce.Message())
+	assert.True(t, errors.Is(ce, io.EOF))
+	assert.Len(t, ce.Details(), 1)
+
+	assert.Same(t, ce, ToConnect(errors.Wrap(ce, "again")))
+}
+
+func TestFromConnect(t *testing.T) {
+	assert.Nil(t, FromConnect(nil))
+
+	err := errors.WithDetails(errors.WithCode(errors.New("no such user"), errors.CodeNotFound), "id", "42", "attempt", 2)
+	got := FromConnect(ToConnect(err))
+	assert.EqualError(t, got, "no such user")
+	assert.Equal(t, errors.CodeNotFound, errors.Code(got))
+	assert.Equal(t, map[string]interface{}{"id": "42", "attempt": float64(2)}, errors.Details(got))
+	var ce *connect.Error
+	assert.True(t, errors.As(got, &ce))
+
+	got = FromConnect(connect.NewError(connect.CodeUnavailable, io.EOF))
+	assert.EqualError(t, got, "EOF")
+	assert.Equal(t, errors.CodeUnavailable, errors.Code(got))
+	assert.Nil(t, errors.Details(got))
+}
+
+func TestInterceptor(t *testing.T) {
+	const procedure = "/test.v1.TestService/Get"
+	handler := connect.NewUnaryHandler(procedure,
+		func(ctx context.Context, req *connect.Request[structpb.Struct]) (*connect.Response[structpb.Struct], error) {
+			err := errors.New("no such user")
+			return nil, errors.WithDetails(errors.WithCode(err, errors.CodePermissionDenied), "id", "42")
+		},
+		connect.WithInterceptors(NewInterceptor()),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := connect.NewClient[structpb.Struct, structpb.Struct](server.Client(), server.URL+procedure,
+		connect.WithInterceptors(NewInterceptor()))
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&structpb.Struct{}))
+	assert.EqualError(t, err, "no such user")
+	assert.Equal(t, errors.CodePermissionDenied, errors.Code(err))
+	assert.Equal(t, map[string]interface{}{"id": "42"}, errors.Details(err))
+	assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+}
+
+func TestInterceptorStreaming(t *testing.T) {
+	const procedure = "/test.v1.TestService/List"
+	handler := connect.NewServerStreamHandler(procedure,
+		func(ctx context.Context, req *connect.Request[structpb.Struct], stream *connect.ServerStream[structpb.Struct]) error {
+			if err := stream.Send(&structpb.Struct{}); err != nil {
+				return err
+			}
+			return errors.WithDetails(errors.WithCode(errors.New("quota exceeded"), errors.CodeResourceExhausted), "limit", "10")
+		},
+		connect.WithInterceptors(NewInterceptor()),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := connect.NewClient[structpb.Struct, structpb.Struct](server.Client(), server.URL+procedure,
+		connect.WithInterceptors(NewInterceptor()))
+	stream, err := client.CallServerStream(context.Background(), connect.NewRequest(&structpb.Struct{}))
+	assert.NoError(t, err)
+	n := 0
+	for stream.Receive() {
+		n++
+	}
+	assert.Equal(t, 1, n)
+	err = stream.Err()
+	assert.EqualError(t, err, "quota exceeded")
+	assert.Equal(t, errors.CodeResourceExhausted, errors.Code(err))
+	assert.Equal(t, map[string]interface{}{"limit": "10"}, errors.Details(err))
+	assert.NoError(t, stream.Close())
+}
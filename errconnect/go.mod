@@ -0,0 +1,16 @@
+module github.com/pkg/errors/errconnect
+
+go 1.21
+
+require (
+	connectrpc.com/connect v1.18.1
+	github.com/pkg/errors v0.9.2-0.20261017021201-46c8d4fdc70d
+	github.com/stretchr/testify v1.8.3
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,68 @@
+// Package errhttp connects the errors of github.com/pkg/errors to net/http.
+package errhttp
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Reporter is called with the request being served and the error a handler
+// panic was converted to.
+type Reporter func(r *http.Request, err error)
+
+// Option configures Recoverer.
+type Option func(*recoverer)
+
+// WithReporter sets the reporter called for every recovered panic. The
+// default logs the error and its stack trace with the log package.
+func WithReporter(report Reporter) Option {
+	return func(h *recoverer) {
+		h.report = report
+	}
+}
+
+// Recoverer returns a handler that serves requests with next and recovers
+// from panics it raises. A recovered panic is converted by errors.WrapPanic
+// into an error whose stack trace starts where the panic was raised, passed
+// to the reporter, and answered with a plain 500 Internal Server Error that
+// does not reveal the panic.
+//
+// Panics with http.ErrAbortHandler are not recovered, so that net/http can
+// abort the response as usual.
+func Recoverer(next http.Handler, opts ...Option) http.Handler {
+	h := &recoverer{next: next, report: logReport}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+type recoverer struct {
+	next   http.Handler
+	report Reporter
+}
+
+func (h *recoverer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		if v == http.ErrAbortHandler {
+			panic(v)
+		}
+		err := errors.WrapPanic(v)
+		if h.report != nil {
+			h.report(r, err)
+		}
+		code := http.StatusInternalServerError
+		http.Error(w, http.StatusText(code), code)
+	}()
+	h.next.ServeHTTP(w, r)
+}
+
+func logReport(r *http.Request, err error) {
+	log.Printf("errhttp: panic serving %s %s: %+v", r.Method, r.URL.Path, err)
+}
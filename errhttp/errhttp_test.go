@@ -0,0 +1,66 @@
+package errhttp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverer(t *testing.T) {
+	var reported error
+	h := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("secret")
+	}), WithReporter(func(r *http.Request, err error) {
+		assert.Equal(t, "/path", r.URL.Path)
+		reported = err
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/path", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "Internal Server Error\n", rec.Body.String())
+	assert.EqualError(t, reported, "panic: secret")
+	assert.Regexp(t, "^panic: secret\ngithub.com/pkg/errors/errhttp.TestRecoverer.func1\t.+/errhttp/errhttp_test.go:17$", fmt.Sprintf("%+v", reported))
+}
+
+func TestRecovererError(t *testing.T) {
+	var reported error
+	h := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(io.EOF)
+	}), WithReporter(func(r *http.Request, err error) {
+		reported = err
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.True(t, errors.Is(reported, io.EOF))
+}
+
+func TestRecovererPassThrough(t *testing.T) {
+	h := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}), WithReporter(func(r *http.Request, err error) {
+		t.Errorf("unexpected report: %v", err)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", rec.Body.String())
+}
+
+func TestRecovererAbort(t *testing.T) {
+	h := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
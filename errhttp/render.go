@@ -0,0 +1,230 @@
+package errhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Status returns the HTTP status code for err, derived from errors.Code as
+// gRPC gateways map codes to statuses. Errors without a code are internal
+// server errors. Status returns http.StatusOK if err is nil.
+func Status(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	switch errors.Code(err) {
+	case errors.CodeOK:
+		return http.StatusOK
+	case errors.CodeCanceled:
+		return 499 // Client Closed Request
+	case errors.CodeInvalidArgument, errors.CodeOutOfRange, errors.CodeFailedPrecondition:
+		return http.StatusBadRequest
+	case errors.CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case errors.CodeNotFound:
+		return http.StatusNotFound
+	case errors.CodeAlreadyExists, errors.CodeAborted:
+		return http.StatusConflict
+	case errors.CodePermissionDenied:
+		return http.StatusForbidden
+	case errors.CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case errors.CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case errors.CodeUnimplemented:
+		return http.StatusNotImplemented
+	case errors.CodeUnavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// Renderer writes errors to HTTP responses. The zero value is ready to use.
+type Renderer struct {
+	// Debug adds the developer-oriented message of the error, its stack
+	// traces and all its details to responses, where otherwise only the
+	// request ID is among the details. It must not be set in production, as
+	// they reveal the internals of the server.
+	Debug bool
+}
+
+// Render writes err to w with the package level Renderer, which does not
+// reveal debugging information.
+func Render(w http.ResponseWriter, r *http.Request, err error) {
+	var rd Renderer
+	rd.Render(w, r, err)
+}
+
+// Render writes err to w, with the status returned by Status, in the format
+// the Accept header of r prefers among application/problem+json (RFC 9457),
+// application/json, text/html and text/plain, the default. The message is
+// the errors.UserMessage of err, or the text of the status if it has none;
+// the code, hints and request ID of the chain are included as well, and
+// the delay of errors.RetryAfter is set as the Retry-After header.
+// If err is nil, Render writes nothing.
+func (rd *Renderer) Render(w http.ResponseWriter, r *http.Request, err error) {
+	if err == nil {
+		return
+	}
+	status := Status(err)
+	msg := errors.UserMessage(err)
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	var debug string
+	var details map[string]interface{}
+	if rd.Debug {
+		debug = fmt.Sprintf("%+v", err)
+		details = jsonDetails(err)
+	} else if id, ok := errors.RequestID(err); ok {
+		details = map[string]interface{}{errors.RequestIDKey: id}
+	}
+	hints := errors.Hints(err)
+	code := errors.Code(err).String()
+
+	h := w.Header()
+	h.Set("X-Content-Type-Options", "nosniff")
+	if d, ok := errors.RetryAfter(err); ok && d > 0 {
+		h.Set("Retry-After", strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10))
+	}
+	switch negotiate(r.Header.Get("Accept")) {
+	case "application/problem+json":
+		h.Set("Content-Type", "application/problem+json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(problem{
+			Type:    "about:blank",
+			Title:   http.StatusText(status),
+			Status:  status,
+			Detail:  msg,
+			Code:    code,
+			Hints:   hints,
+			Details: details,
+			Debug:   debug,
+		})
+	case "application/json":
+		h.Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(struct {
+			Error jsonError `json:"error"`
+		}{jsonError{
+			Code:    code,
+			Message: msg,
+			Hints:   hints,
+			Details: details,
+			Debug:   debug,
+		}})
+	case "text/html":
+		h.Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		title := html.EscapeString(strconv.Itoa(status) + " " + http.StatusText(status))
+		fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><title>%s</title></head><body>\n<h1>%s</h1>\n<p>%s</p>\n",
+			title, title, html.EscapeString(msg))
+		for _, hint := range hints {
+			fmt.Fprintf(w, "<p>%s</p>\n", html.EscapeString(hint))
+		}
+		if debug != "" {
+			fmt.Fprintf(w, "<pre>%s</pre>\n", html.EscapeString(debug))
+		}
+		io.WriteString(w, "</body></html>\n")
+	default:
+		h.Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		io.WriteString(w, msg+"\n")
+		for _, hint := range hints {
+			io.WriteString(w, hint+"\n")
+		}
+		if debug != "" {
+			io.WriteString(w, "\n"+debug+"\n")
+		}
+	}
+}
+
+type problem struct {
+	Type    string                 `json:"type"`
+	Title   string                 `json:"title"`
+	Status  int                    `json:"status"`
+	Detail  string                 `json:"detail"`
+	Code    string                 `json:"code"`
+	Hints   []string               `json:"hints,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Debug   string                 `json:"debug,omitempty"`
+}
+
+type jsonError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Hints   []string               `json:"hints,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Debug   string                 `json:"debug,omitempty"`
+}
+
+// jsonDetails returns the details of err, with the values that cannot be
+// encoded as JSON formatted by fmt.Sprint.
+func jsonDetails(err error) map[string]interface{} {
+	details := errors.Details(err)
+	for k, v := range details {
+		if _, err := json.Marshal(v); err != nil {
+			details[k] = fmt.Sprint(v)
+		}
+	}
+	return details
+}
+
+// renderTypes are the media types Render can write, in the order they are
+// preferred when the Accept header ranks them equally.
+var renderTypes = []string{"application/problem+json", "application/json", "text/html", "text/plain"}
+
+// negotiate returns the one of renderTypes that accept ranks highest, or
+// text/plain if it accepts none of them.
+func negotiate(accept string) string {
+	best, bestQ := "text/plain", 0.0
+	for _, t := range renderTypes {
+		if q := quality(accept, t); q > bestQ {
+			best, bestQ = t, q
+		}
+	}
+	return best
+}
+
+// quality returns the quality accept gives to the media type t, taking
+// the most specific range matching t into account.
+func quality(accept, t string) float64 {
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+		s := -1
+		switch {
+		case mediaRange == t:
+			s = 2
+		case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(t, mediaRange[:len(mediaRange)-1]):
+			s = 1
+		case mediaRange == "*/*":
+			s = 0
+		}
+		if s <= specificity {
+			continue
+		}
+		specificity, q = s, 1
+		for _, p := range params[1:] {
+			if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok && k == "q" {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+	}
+	if specificity == 0 && t != "text/plain" {
+		// Clients that accept anything get the default.
+		q /= 2
+	}
+	return q
+}
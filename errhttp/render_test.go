@@ -0,0 +1,99 @@
+package errhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	assert.Equal(t, http.StatusOK, Status(nil))
+	assert.Equal(t, http.StatusInternalServerError, Status(io.EOF))
+	assert.Equal(t, http.StatusNotFound, Status(errors.WithCode(io.EOF, errors.CodeNotFound)))
+	assert.Equal(t, http.StatusTooManyRequests, Status(errors.WithCode(io.EOF, errors.CodeResourceExhausted)))
+	assert.Equal(t, http.StatusInternalServerError, Status(errors.WithCode(io.EOF, errors.CodeDataLoss)))
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept, want string
+	}{
+		{"", "text/plain"},
+		{"*/*", "text/plain"},
+		{"application/json", "application/json"},
+		{"application/problem+json, application/json;q=0.9", "application/problem+json"},
+		{"application/problem+json;q=0.5, application/json", "application/json"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "text/html"},
+		{"application/*", "application/problem+json"},
+		{"image/png", "text/plain"},
+		{"text/plain;q=0, application/json;q=0.1", "application/json"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, negotiate(tt.accept), "negotiate(%q)", tt.accept)
+	}
+}
+
+func render(rd *Renderer, accept string, err error) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", accept)
+	rec := httptest.NewRecorder()
+	rd.Render(rec, r, err)
+	return rec
+}
+
+func TestRender(t *testing.T) {
+	err := errors.WithDetails(errors.WithHint(errors.WithUserMessage(errors.WithCode(
+		errors.New("user 42 missing from users table"), errors.CodeNotFound),
+		"No such user."), "Check the <ID>."), "id", 42, "ch", make(chan int))
+
+	rec := render(&Renderer{}, "application/json", err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"error":{"code":"NotFound","message":"No such user.","hints":["Check the \u003cID\u003e."]}}`+"\n", rec.Body.String())
+
+	rec = render(&Renderer{Debug: true}, "application/json", err)
+	assert.Regexp(t, `"details":{"ch":"0x[0-9a-f]+","id":42}`, rec.Body.String())
+
+	rec = render(&Renderer{}, "application/problem+json", errors.WithCode(io.EOF, errors.CodeUnavailable))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"Service Unavailable","code":"Unavailable"}`+"\n", rec.Body.String())
+
+	rec = render(&Renderer{}, "text/html", err)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "<h1>404 Not Found</h1>\n<p>No such user.</p>\n<p>Check the &lt;ID&gt;.</p>\n")
+	assert.NotContains(t, rec.Body.String(), "users table")
+
+	rec = render(&Renderer{}, "", err)
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "No such user.\nCheck the <ID>.\n", rec.Body.String())
+
+	rec = render(&Renderer{Debug: true}, "", err)
+	assert.Regexp(t, "^No such user.\nCheck the <ID>.\n\nuser 42 missing from users table\ngithub.com/pkg/errors/errhttp.TestRender\t.+/errhttp/render_test.go:\\d+\n$", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	Render(rec, httptest.NewRequest("GET", "/", nil), nil)
+	assert.Equal(t, 0, rec.Body.Len())
+}
+
+func TestRenderDetails(t *testing.T) {
+	err := errors.WithCode(errors.New("exit status 1"), errors.CodeUnavailable)
+	err = errors.WithDetails(err, errors.StderrKey, "password incorrect")
+	err = errors.WithPayload(err, "request", []byte(`{"password":"hunter2"}`))
+	err = errors.WithRetryAfter(errors.WithRequestID(err, "req-1"), 1500*time.Millisecond)
+
+	for _, accept := range []string{"application/json", "application/problem+json"} {
+		rec := render(&Renderer{}, accept, err)
+		assert.Contains(t, rec.Body.String(), `"details":{"errors.request_id":"req-1"}`)
+		assert.NotContains(t, rec.Body.String(), "password")
+		assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+	}
+	rec := render(&Renderer{Debug: true}, "application/json", err)
+	assert.Contains(t, rec.Body.String(), errors.StderrKey)
+	assert.Contains(t, rec.Body.String(), errors.PayloadKeyPrefix+"request")
+}
@@ -0,0 +1,112 @@
+package errhttp
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The detail keys FromResponse stores the request and response under.
+const (
+	MethodKey = "http.method"
+	URLKey    = "http.url"
+	StatusKey = "http.status_code"
+)
+
+// maxBody is the number of bytes of a response body FromResponse reads.
+const maxBody = 64 << 10
+
+// FromResponse returns an error describing resp, a response with an error
+// status, with a stack trace at the point FromResponse is called:
+//
+//	GET https://api.example.com/users/42: 404 Not Found
+//
+// The error carries the status, the method and the URL, without its
+// password, as details, the code CodeFromStatus maps the status to, and the
+// Retry-After delay the server asked for, if any. The beginning of the
+// body is attached with errors.WithPayload as the payload "response". The
+// body is read, up to 64 KiB, but not closed.
+// If the status of resp is below 400, FromResponse returns nil.
+func FromResponse(resp *http.Response) error {
+	if resp.StatusCode < 400 {
+		return nil
+	}
+	msg := resp.Status
+	kv := []interface{}{StatusKey, resp.StatusCode}
+	if req := resp.Request; req != nil && req.URL != nil {
+		url := req.URL.Redacted()
+		msg = req.Method + " " + url + ": " + msg
+		kv = append(kv, MethodKey, req.Method, URLKey, url)
+	}
+	err := errors.WithDetails(errors.GlobalApi().New(msg), kv...)
+	if resp.Body != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+		if len(body) > 0 {
+			err = errors.WithPayload(err, "response", body)
+		}
+	}
+	if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+		err = errors.WithRetryAfter(err, d)
+	}
+	return errors.WithCode(err, CodeFromStatus(resp.StatusCode))
+}
+
+// CodeFromStatus returns the code of the failures reported by the HTTP
+// status code, the converse of Status. Statuses that do not report a
+// failure map to errors.CodeOK, and those it does not know to
+// errors.CodeUnknown.
+func CodeFromStatus(status int) errors.ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return errors.CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return errors.CodeUnauthenticated
+	case http.StatusForbidden:
+		return errors.CodePermissionDenied
+	case http.StatusNotFound:
+		return errors.CodeNotFound
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return errors.CodeDeadlineExceeded
+	case http.StatusConflict:
+		return errors.CodeAborted
+	case http.StatusPreconditionFailed:
+		return errors.CodeFailedPrecondition
+	case http.StatusRequestedRangeNotSatisfiable:
+		return errors.CodeOutOfRange
+	case http.StatusTooManyRequests:
+		return errors.CodeResourceExhausted
+	case 499: // Client Closed Request
+		return errors.CodeCanceled
+	case http.StatusInternalServerError:
+		return errors.CodeInternal
+	case http.StatusNotImplemented:
+		return errors.CodeUnimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return errors.CodeUnavailable
+	}
+	if status < 400 {
+		return errors.CodeOK
+	}
+	return errors.CodeUnknown
+}
+
+// retryAfter parses the value of a Retry-After header, in seconds or as an
+// HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,90 @@
+package errhttp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			io.WriteString(w, "fine")
+		case "/busy":
+			w.Header().Set("Retry-After", "30")
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+		default:
+			http.Error(w, `{"error":"no such user"}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ok")
+	assert.NoError(t, err)
+	assert.NoError(t, FromResponse(resp))
+	resp.Body.Close()
+
+	resp, err = http.Get(srv.URL + "/users/42")
+	assert.NoError(t, err)
+	err = FromResponse(resp)
+	resp.Body.Close()
+	assert.EqualError(t, err, "GET "+srv.URL+"/users/42: 404 Not Found")
+	assert.True(t, errors.IsNotFound(err))
+	assert.Equal(t, http.StatusNotFound, Status(err))
+	details := errors.Details(err)
+	assert.Equal(t, "GET", details[MethodKey])
+	assert.Equal(t, srv.URL+"/users/42", details[URLKey])
+	assert.Equal(t, 404, details[StatusKey])
+	assert.Equal(t, "{\"error\":\"no such user\"}\n", string(errors.Payloads(err)["response"].Body))
+	assert.Regexp(t, "\ngithub.com/pkg/errors/errhttp.TestFromResponse\t.+/errhttp/response_test.go:\\d+$", fmt.Sprintf("%+v", errors.Cause(errors.RootCause(err))))
+
+	resp, err = http.Get(srv.URL + "/busy")
+	assert.NoError(t, err)
+	err = FromResponse(resp)
+	resp.Body.Close()
+	assert.Equal(t, errors.CodeUnavailable, errors.Code(err))
+	d, ok := errors.RetryAfter(err)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, d)
+
+	err = FromResponse(&http.Response{Status: "500 Internal Server Error", StatusCode: 500, Body: io.NopCloser(strings.NewReader(""))})
+	assert.EqualError(t, err, "500 Internal Server Error")
+	assert.Nil(t, errors.Payloads(err))
+}
+
+func TestFromResponseGlobalApi(t *testing.T) {
+	defer errors.SnapshotGlobalApi()()
+	errors.SetGlobalApi(errors.NewErrorsApi(errors.ApiConfig{CallerSkip: 2, DisableStack: true}))
+
+	err := FromResponse(&http.Response{Status: "502 Bad Gateway", StatusCode: 502})
+	assert.EqualError(t, err, "502 Bad Gateway")
+	assert.Equal(t, "502 Bad Gateway", fmt.Sprintf("%+v", errors.Cause(errors.RootCause(err))))
+}
+
+func TestCodeFromStatus(t *testing.T) {
+	assert.Equal(t, errors.CodeOK, CodeFromStatus(http.StatusNoContent))
+	assert.Equal(t, errors.CodeUnauthenticated, CodeFromStatus(http.StatusUnauthorized))
+	assert.Equal(t, errors.CodeDeadlineExceeded, CodeFromStatus(http.StatusGatewayTimeout))
+	assert.Equal(t, errors.CodeUnknown, CodeFromStatus(http.StatusTeapot))
+	for _, code := range []errors.ErrorCode{errors.CodeNotFound, errors.CodePermissionDenied, errors.CodeResourceExhausted} {
+		assert.Equal(t, code, CodeFromStatus(Status(errors.WithCode(io.EOF, code))))
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	_, ok := retryAfter("")
+	assert.False(t, ok)
+	_, ok = retryAfter("soon")
+	assert.False(t, ok)
+	d, ok := retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.InDelta(t, time.Hour, d, float64(2*time.Second))
+}
@@ -62,6 +62,9 @@
 //	%v    see %s
 //	%+v   extended format. Each Frame of the error's StackTrace will
 //	      be printed in detail.
+//	%+.3v like %+v, but prints at most 3 frames of each StackTrace.
+//	%-v   print only the outermost message, without those of the
+//	      causes, as OutermostMessage does.
 //
 // # Retrieving the stack trace of an error or wrapper
 //
@@ -96,66 +99,89 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 )
 
 // New returns an error with the supplied message.
 // New also records the stack trace at the point it was called.
 func New(message string) error {
-	return globalErrorsApi.New(message)
+	return globalApi().New(message)
 }
 
 // Errorf formats according to a format specifier and returns the string
 // as a value that satisfies error.
 // Errorf also records the stack trace at the point it was called.
 func Errorf(format string, args ...interface{}) error {
-	return globalErrorsApi.Errorf(format, args...)
+	return globalApi().Errorf(format, args...)
+}
+
+// NewPlain returns an error with the supplied message, like New, but does
+// not record a stack trace. It is meant for hot paths and sentinel values
+// that still want this package's formatting behaviour.
+func NewPlain(message string) error {
+	return globalApi().NewPlain(message)
 }
 
 // fundamental is an error that has a message and a stack, but no caller.
+// Errors created by NewPlain have a nil stack.
 type fundamental struct {
 	msg string
 	*stack
+	format *Config
+	render *renderConfig
+	tmpl   *msgTemplate
 }
 
-func (f *fundamental) Error() string { return f.msg }
+func (f *fundamental) Error() string { return f.render.truncate(f.layerMessage()) }
+
+func (f *fundamental) layerMessage() string { return f.render.message(f.msg) }
+func (f *fundamental) layerStack() *stack   { return f.stack }
+func (f *fundamental) layerFormat() *Config { return f.format }
 
 func (f *fundamental) Format(s fmt.State, verb rune) {
 	switch verb {
 	case 'v':
+		if s.Flag('-') {
+			io.WriteString(s, OutermostMessage(f))
+			return
+		}
 		if s.Flag('+') {
-			if f.msg != "" {
-				io.WriteString(s, f.msg)
-				io.WriteString(s, globalOptions.MsgSep)
+			io.WriteString(s, f.layerMessage())
+			if len(f.stack.frames(s)) > 0 {
+				opts := formatOptions(f.format)
+				if f.msg != "" {
+					io.WriteString(s, opts.MsgSep)
+				}
+				f.stack.formatWith(s, opts)
 			}
-			f.stack.Format(s, verb)
 			return
 		}
 		fallthrough
 	case 's':
-		io.WriteString(s, f.msg)
+		io.WriteString(s, f.Error())
 	case 'q':
-		fmt.Fprintf(s, "%q", f.msg)
+		fmt.Fprintf(s, "%q", f.Error())
 	}
 }
 
-func (f *fundamental) errorLine(stack bool) string {
-	var buf strings.Builder
-	if f.msg != "" {
-		buf.WriteString(f.msg)
-	}
-	if f.msg != "" && stack {
-		buf.WriteString(globalOptions.MsgSep)
-	}
-	if stack {
-		buf.WriteString(fmt.Sprintf("%+v", f.stack))
-	}
-	return buf.String()
+func (f *fundamental) ErrorLineV(level Verbosity) string {
+	return newLineConfig(lineFormat(f.format), LineVerbosity(level)).line(f)
 }
 
 // WithStack annotates err with a stack trace at the point WithStack was called.
 // If err is nil, WithStack returns nil.
 func WithStack(err error) error {
-	return globalErrorsApi.WithStack(err)
+	return globalApi().WithStack(err)
+}
+
+// WithStackTrace annotates err with st, a stack trace captured elsewhere,
+// such as one taken from another error or while recovering from a panic,
+// in place of the stack of the call. The frames of st must belong to this
+// process. The stack formats with %+v and Lines like one captured by
+// WithStack. If err is nil, WithStackTrace returns nil; if st is empty, it
+// returns err.
+func WithStackTrace(err error, st StackTrace) error {
+	return globalErrorsApi().WithStackTrace(err, st)
 }
 
 type withStack struct {
@@ -163,84 +189,183 @@ type withStack struct {
 	*stack
 }
 
+func (w *withStack) layerStack() *stack { return w.stack }
+
+func (w *withStack) StackTrace() StackTrace { return w.stack.StackTrace() }
+
+func (w *withStack) rewrap(cause error) error {
+	c := *w
+	c.withMessage = *w.withMessage.rewrap(cause).(*withMessage)
+	return &c
+}
+
 func (w *withStack) Format(s fmt.State, verb rune) {
 	switch verb {
 	case 'v':
+		if s.Flag('-') {
+			io.WriteString(s, OutermostMessage(w))
+			return
+		}
 		if s.Flag('+') {
+			opts := formatOptions(w.format)
+			sep := ""
 			if w.Cause() != nil {
-				fmt.Fprintf(s, "%+v", w.Cause())
-				fmt.Fprintf(s, globalOptions.StackSep)
+				formatCause(s, w.Cause())
+				sep = opts.StackSep
 			}
 			if w.msg != "" {
-				io.WriteString(s, w.msg)
-				fmt.Fprintf(s, globalOptions.StackSep)
+				io.WriteString(s, sep)
+				io.WriteString(s, w.layerMessage())
+				sep = opts.StackSep
+			}
+			if len(w.stack.frames(s)) > 0 {
+				io.WriteString(s, sep)
+				w.stack.formatWith(s, opts)
 			}
-			w.stack.Format(s, verb)
 			return
 		}
 		fallthrough
 	case 's':
-		io.WriteString(s, w.Error())
+		if w.render.truncates() {
+			io.WriteString(s, w.Error())
+			return
+		}
+		writeError(s, w)
 	case 'q':
 		fmt.Fprintf(s, "%q", w.Error())
 	}
 }
 
-func (w *withStack) errorLine(stack bool) string {
-	if !stack {
-		return w.msg
-	}
-	var buf strings.Builder
-	if w.msg != "" {
-		buf.WriteString(w.msg)
-		buf.WriteString(globalOptions.MsgSep)
-	}
-	buf.WriteString(fmt.Sprintf("%+v", w.stack))
-	return buf.String()
+func (w *withStack) ErrorLineV(level Verbosity) string {
+	return newLineConfig(lineFormat(w.format), LineVerbosity(level)).line(w)
 }
 
 // Wrap returns an error annotating err with a stack trace
 // at the point Wrap is called, and the supplied message.
 // If err is nil, Wrap returns nil.
 func Wrap(err error, message string) error {
-	return globalErrorsApi.Wrap(err, message)
+	return globalApi().Wrap(err, message)
+}
+
+// WrapOrNew is like Wrap, but if err is nil, it returns a new error with
+// the supplied message and a stack trace at the point WrapOrNew is called,
+// as New does, rather than nil.
+func WrapOrNew(err error, message string) error {
+	return globalErrorsApi().WrapOrNew(err, message)
 }
 
 // Wrapf returns an error annotating err with a stack trace
 // at the point Wrapf is called, and the format specifier.
 // If err is nil, Wrapf returns nil.
 func Wrapf(err error, format string, args ...interface{}) error {
-	return globalErrorsApi.Wrapf(err, format, args...)
+	return globalApi().Wrapf(err, format, args...)
+}
+
+// WrapIf returns Wrap(err, message) if cond is true, and err unchanged
+// otherwise. It is meant for annotations that are only worth their cost
+// in some configurations, such as when debugging is enabled.
+func WrapIf(cond bool, err error, message string) error {
+	return globalApi().WrapIf(cond, err, message)
+}
+
+// WrapfIf returns Wrapf(err, format, args...) if cond is true, and err
+// unchanged otherwise.
+func WrapfIf(cond bool, err error, format string, args ...interface{}) error {
+	return globalApi().WrapfIf(cond, err, format, args...)
+}
+
+// WrapFn returns an error annotating err with a stack trace at the point
+// WrapFn is called, and the name of the calling function as the message,
+// such as "server.(*Server).handle".
+// If err is nil, WrapFn returns nil.
+func WrapFn(err error) error {
+	return globalApi().WrapFn(err)
+}
+
+// WrapPlain returns an error annotating err with the supplied message, like
+// Wrap, but does not record a stack trace.
+// If err is nil, WrapPlain returns nil.
+func WrapPlain(err error, message string) error {
+	return globalApi().WrapPlain(err, message)
 }
 
 // WithMessage annotates err with a new message.
 // If err is nil, WithMessage returns nil.
 func WithMessage(err error, message string) error {
-	return globalErrorsApi.WithMessage(err, message)
+	return globalApi().WithMessage(err, message)
 }
 
 // WithMessagef annotates err with the format specifier.
 // If err is nil, WithMessagef returns nil.
 func WithMessagef(err error, format string, args ...interface{}) error {
-	return globalErrorsApi.WithMessagef(err, format, args...)
+	return globalApi().WithMessagef(err, format, args...)
 }
 
 type withMessage struct {
-	cause error
-	msg   string
+	cause  error
+	msg    string
+	cache  *errorCache
+	format *Config
+	render *renderConfig
+	tmpl   *msgTemplate
 }
 
 func (w *withMessage) Error() string {
 	if w.cause == nil {
-		return w.msg
+		return w.render.truncate(w.layerMessage())
 	}
-	if w.msg == "" {
-		return w.cause.Error()
+	if s, ok := w.cachedError(); ok {
+		return s
 	}
-	return w.msg + ": " + w.cause.Error()
+	return w.composeError()
 }
+
+func (w *withMessage) composeError() string {
+	var buf strings.Builder
+	writeChain(&buf, w, true)
+	return w.render.truncate(buf.String())
+}
+
+// cachedError returns the cached Error() string of w, if the api that
+// created w caches them.
+func (w *withMessage) cachedError() (string, bool) {
+	if w.cache == nil {
+		return "", false
+	}
+	w.cache.once.Do(func() {
+		w.cache.s = w.composeError()
+	})
+	return w.cache.s, true
+}
+
+// errorCache holds the Error() string of a withMessage created by an api
+// with ErrorStringEager or ErrorStringLazy.
+type errorCache struct {
+	once sync.Once
+	s    string
+}
+
 func (w *withMessage) Cause() error { return w.cause }
 
+func (w *withMessage) layerMessage() string { return w.render.message(w.msg) }
+func (w *withMessage) layerStack() *stack   { return nil }
+func (w *withMessage) layerFormat() *Config { return w.format }
+
+func (w *withMessage) rewrap(cause error) error {
+	c := *w
+	c.cause = cause
+	if c.cache != nil {
+		// The cached string is that of the old cause.
+		c.cache = &errorCache{}
+	}
+	return &c
+}
+
+// StackTrace returns the stack trace of the nearest error in w's chain that
+// has one, so that the stack of an error stays reachable through the
+// wrappers that only add a message to it.
+func (w *withMessage) StackTrace() StackTrace { return nearestStack(w.cause) }
+
 // Unwrap provides compatibility for Go 1.13 error chains.
 func (w *withMessage) Unwrap() error {
 	return w.cause
@@ -249,22 +374,83 @@ func (w *withMessage) Unwrap() error {
 func (w *withMessage) Format(s fmt.State, verb rune) {
 	switch verb {
 	case 'v':
+		if s.Flag('-') {
+			io.WriteString(s, OutermostMessage(w))
+			return
+		}
 		if s.Flag('+') {
 			if w.Cause() != nil {
-				fmt.Fprintf(s, "%+v", w.Cause())
-				fmt.Fprintf(s, globalOptions.StackSep)
+				formatCause(s, w.Cause())
+				io.WriteString(s, formatOptions(w.format).StackSep)
 			}
-			io.WriteString(s, w.msg)
+			io.WriteString(s, w.layerMessage())
 			return
 		}
 		fallthrough
 	case 's', 'q':
-		io.WriteString(s, w.Error())
+		if w.render.truncates() {
+			io.WriteString(s, w.Error())
+			return
+		}
+		writeError(s, w)
 	}
 }
 
-func (w *withMessage) errorLine(stack bool) string {
-	return w.msg
+func (w *withMessage) ErrorLineV(level Verbosity) string {
+	return w.layerMessage()
+}
+
+// writeError writes err.Error() to w. Layers of this package are walked
+// in a single pass instead of recursing through Error(), so composing the
+// message of a deep chain does not allocate a string per layer.
+func writeError(w io.Writer, err error) {
+	writeChain(w, err, false)
+}
+
+// writeChain implements writeError. Layers that cache their Error() string
+// are written from the cache, except for err itself if skipCache is set.
+func writeChain(w io.Writer, err error, skipCache bool) {
+	for {
+		if c, ok := err.(interface{ cachedError() (string, bool) }); ok && !skipCache {
+			if s, ok := c.cachedError(); ok {
+				io.WriteString(w, s)
+				return
+			}
+		}
+		skipCache = false
+		l, ok := err.(layer)
+		if !ok {
+			io.WriteString(w, err.Error())
+			return
+		}
+		msg := l.layerMessage()
+		io.WriteString(w, msg)
+		cause := unwrapOnce(err)
+		if cause == nil {
+			return
+		}
+		if msg != "" {
+			io.WriteString(w, ": ")
+		}
+		err = cause
+	}
+}
+
+// formatCause writes the %+v form of cause to s. Causes that implement
+// fmt.Formatter are passed the precision of s, if any, which limits the
+// frames printed per stack. Causes that implement Liner but not
+// fmt.Formatter are rendered at VerbosityDebug.
+func formatCause(s fmt.State, cause error) {
+	_, formatter := cause.(fmt.Formatter)
+	if l, ok := cause.(Liner); ok && !formatter {
+		io.WriteString(s, l.ErrorLineV(VerbosityDebug))
+		return
+	}
+	if prec, ok := s.Precision(); ok && formatter {
+		fmt.Fprintf(s, "%+.*v", prec, cause)
+		return
+	}
+	fmt.Fprintf(s, "%+v", cause)
 }
 
 // Cause returns the underlying cause of the error, if possible.
@@ -292,3 +478,49 @@ func Cause(err error) error {
 	}
 	return err
 }
+
+// rewrapper is implemented by the wrappers of this package, which can
+// return a copy of themselves around a different cause.
+type rewrapper interface {
+	rewrap(cause error) error
+}
+
+// annotation is embedded by wrappers that attach information to their
+// cause without adding a message or a stack. They are invisible in the
+// Error() string and in Lines, and format as their cause does.
+type annotation struct {
+	cause error
+}
+
+func (a *annotation) Error() string { return a.cause.Error() }
+func (a *annotation) Cause() error  { return a.cause }
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+func (a *annotation) Unwrap() error { return a.cause }
+
+func (a *annotation) layerMessage() string { return "" }
+func (a *annotation) layerStack() *stack   { return nil }
+func (a *annotation) layerFormat() *Config { return nil }
+
+// StackTrace returns the stack trace of the nearest error in a's chain that
+// has one, as it does for WithMessage.
+func (a *annotation) StackTrace() StackTrace { return nearestStack(a.cause) }
+
+func (a *annotation) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('-') {
+			io.WriteString(s, OutermostMessage(a))
+			return
+		}
+		if s.Flag('+') {
+			formatCause(s, a.cause)
+			return
+		}
+		fallthrough
+	case 's':
+		writeError(s, a.cause)
+	case 'q':
+		fmt.Fprintf(s, "%q", a.cause.Error())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"regexp"
 	"testing"
 )
 
@@ -249,3 +250,237 @@ func TestErrorEquality(t *testing.T) {
 		}
 	}
 }
+
+func TestFormatWithoutArgs(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{Errorf("no verbs"), "no verbs"},
+		{Errorf("100%%"), "100%"},
+		{Errorf("%d"), "%!d(MISSING)"},
+		{Wrapf(io.EOF, "no verbs"), "no verbs: EOF"},
+		{Wrapf(io.EOF, "100%%"), "100%: EOF"},
+		{WithMessagef(io.EOF, "no verbs"), "no verbs: EOF"},
+		{WithMessagef(io.EOF, "100%%"), "100%: EOF"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("got: %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestDeepError(t *testing.T) {
+	var err error = New("root")
+	want := "root"
+	for i := 0; i < 100; i++ {
+		if i%3 == 0 {
+			err = WithStack(err)
+			continue
+		}
+		err = Wrapf(err, "layer %d", i)
+		want = fmt.Sprintf("layer %d: ", i) + want
+	}
+	err = WithMessage(fmt.Errorf("foreign: %w", err), "outer")
+	want = "outer: foreign: " + want
+
+	if got := err.Error(); got != want {
+		t.Errorf("got: %q, want %q", got, want)
+	}
+	if got := Wrap(New(""), "empty").Error(); got != "empty: " {
+		t.Errorf("got: %q, want %q", got, "empty: ")
+	}
+}
+
+func TestPlain(t *testing.T) {
+	plain := NewPlain("plain")
+	tests := []struct {
+		err    error
+		format string
+		want   string
+	}{
+		{plain, "%s", "plain"},
+		{plain, "%v", "plain"},
+		{plain, "%+v", "plain"},
+		{plain, "%q", `"plain"`},
+		{WrapPlain(plain, "wrapped"), "%v", "wrapped: plain"},
+		{WrapPlain(plain, "wrapped"), "%+v", "plain\nwrapped"},
+		{WrapPlain(io.EOF, "wrapped"), "%+v", "EOF\nwrapped"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, tt.err); got != tt.want {
+			t.Errorf("fmt.Sprintf(%q, err): got: %q, want %q", tt.format, got, tt.want)
+		}
+	}
+	if got := Lines(WrapPlain(plain, "wrapped"), true); !reflect.DeepEqual(got, []string{"wrapped", "plain"}) {
+		t.Errorf("Lines: got: %q", got)
+	}
+	if st := plain.(interface{ StackTrace() StackTrace }).StackTrace(); len(st) != 0 {
+		t.Errorf("NewPlain recorded a stack: %v", st)
+	}
+	if got := WrapPlain(nil, "wrapped"); got != nil {
+		t.Errorf("WrapPlain(nil, \"wrapped\"): got %#v, expected nil", got)
+	}
+}
+
+func TestWrapIf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{WrapIf(true, io.EOF, "read error"), "read error: EOF"},
+		{WrapIf(false, io.EOF, "read error"), "EOF"},
+		{WrapfIf(true, io.EOF, "read error %d", 1), "read error 1: EOF"},
+		{WrapfIf(false, io.EOF, "read error %d", 1), "EOF"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("got: %q, want %q", got, tt.want)
+		}
+	}
+	if got := WrapIf(true, nil, "no error"); got != nil {
+		t.Errorf("WrapIf(true, nil, \"no error\"): got %#v, expected nil", got)
+	}
+	if got := WrapfIf(true, nil, "no error"); got != nil {
+		t.Errorf("WrapfIf(true, nil, \"no error\"): got %#v, expected nil", got)
+	}
+	if got := WrapIf(false, io.EOF, "no error"); got != io.EOF {
+		t.Errorf("WrapIf(false, io.EOF, \"no error\"): got %#v, expected io.EOF", got)
+	}
+}
+
+func wrapFnHelper() error {
+	return WrapFn(io.EOF)
+}
+
+func TestWrapFn(t *testing.T) {
+	if got, want := wrapFnHelper().Error(), "errors.wrapFnHelper: EOF"; got != want {
+		t.Errorf("WrapFn: got: %q, want %q", got, want)
+	}
+	if got := WrapFn(nil); got != nil {
+		t.Errorf("WrapFn(nil): got %#v, expected nil", got)
+	}
+}
+
+func TestStackTraceDelegation(t *testing.T) {
+	type stackTracer interface {
+		StackTrace() StackTrace
+	}
+	err := New("foo")
+	want := err.(stackTracer).StackTrace()
+
+	tests := []error{
+		WithMessage(err, "bar"),
+		WrapPlain(err, "bar"),
+		WithCode(err, CodeInternal),
+		WithDetails(WithMessage(err, "bar"), "k", "v"),
+		WithMessage(causeOnly{err}, "bar"),
+	}
+	for _, tt := range tests {
+		st, ok := tt.(stackTracer)
+		if !ok {
+			t.Fatalf("expected %#v to implement StackTrace() StackTrace", tt)
+		}
+		if got := st.StackTrace(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: StackTrace() = %v, want %v", tt, got, want)
+		}
+	}
+
+	if st := WithMessage(io.EOF, "bar").(stackTracer).StackTrace(); st != nil {
+		t.Errorf("WithMessage(io.EOF).StackTrace() = %v, want nil", st)
+	}
+	wrapped := Wrap(err, "bar")
+	if got := wrapped.(stackTracer).StackTrace(); reflect.DeepEqual(got, want) {
+		t.Errorf("Wrap(err).StackTrace() = %v, want its own stack", got)
+	}
+}
+
+func TestFormatPrecision(t *testing.T) {
+	api := NewErrorsApi(WithMaxDepth(8))
+	err := api.Wrap(api.New("inner"), "outer")
+	frame := "github.com/pkg/errors.TestFormatPrecision\t.+/github.com/pkg/errors/errors_test.go:\\d+"
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"%+.1v", "^inner\n" + frame + "\nouter\n" + frame + "$"},
+		{"%+.0v", "^inner\nouter$"},
+		{"%+.2v", "^inner\n" + frame + "\ntesting.tRunner\t.+\nouter\n" + frame + "\ntesting.tRunner\t.+$"},
+	}
+	for _, tt := range tests {
+		got := fmt.Sprintf(tt.format, err)
+		if !regexp.MustCompile(tt.want).MatchString(got) {
+			t.Errorf("fmt.Sprintf(%q, err): got: %q, want: %q", tt.format, got, tt.want)
+		}
+	}
+
+	st := err.(*withStack).StackTrace()
+	got := fmt.Sprintf("%+.1v", st)
+	if want := "^\n" + frame + "$"; !regexp.MustCompile(want).MatchString(got) {
+		t.Errorf("fmt.Sprintf(%%+.1v, st): got: %q, want: %q", got, want)
+	}
+	got = fmt.Sprintf("%+.1v", Join(WithCode(err, CodeInternal), io.EOF))
+	if want := "^inner\n" + frame + "\nouter\n" + frame + "\nEOF$"; !regexp.MustCompile(want).MatchString(got) {
+		t.Errorf("fmt.Sprintf(%%+.1v, joined): got: %q, want: %q", got, want)
+	}
+}
+
+func TestWithStackTrace(t *testing.T) {
+	origin := New("origin")
+	st := origin.(*fundamental).StackTrace()
+
+	if err := WithStackTrace(nil, st); err != nil {
+		t.Errorf("WithStackTrace(nil, st) = %v, want nil", err)
+	}
+	if err := WithStackTrace(io.EOF, nil); err != io.EOF {
+		t.Errorf("WithStackTrace(io.EOF, nil) = %#v, want io.EOF", err)
+	}
+
+	err := WithStackTrace(io.EOF, st)
+	if got := err.Error(); got != "EOF" {
+		t.Errorf("Error() = %q, want %q", got, "EOF")
+	}
+	if got := err.(*withStack).StackTrace(); !reflect.DeepEqual(got, st) {
+		t.Errorf("StackTrace() = %v, want %v", got, st)
+	}
+	got, want := fmt.Sprintf("%+v", err), "EOF"+fmt.Sprintf("%+v", origin)[len("origin"):]
+	if got != want {
+		t.Errorf("fmt.Sprintf(%%+v, err): got: %q, want: %q", got, want)
+	}
+
+	var recovered StackTrace
+	func() {
+		defer func() {
+			recovered = nearestStack(WrapPanic(recover()))
+		}()
+		panic("boom")
+	}()
+	err = WithStackTrace(Wrap(io.EOF, "read"), recovered)
+	if got := TopFrames(err, 1); !reflect.DeepEqual(got, recovered) {
+		t.Errorf("TopFrames(err, 1) = %v, want %v", got, recovered)
+	}
+}
+
+func TestFormatOutermost(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{New("error"), "error"},
+		{Wrap(io.EOF, "read config"), "read config"},
+		{WithMessage(Wrap(io.EOF, "read"), "load config"), "load config"},
+		{WithStack(Wrap(io.EOF, "read")), "read"},
+		{WithCode(Wrap(io.EOF, "read"), CodeNotFound), "read"},
+		{WithStack(fmt.Errorf("dial: %w", io.EOF)), "dial: EOF"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf("%-v", tt.err); got != tt.want {
+			t.Errorf("fmt.Sprintf(%%-v, %v): got: %q, want: %q", tt.err, got, tt.want)
+		}
+	}
+}
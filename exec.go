@@ -0,0 +1,94 @@
+//go:build !plan9
+// +build !plan9
+
+package errors
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// StderrKey is the detail key WithStderr and WrapCmd store the output of
+// failed commands under.
+const StderrKey = "errors.stderr"
+
+// maxStderr is the number of trailing bytes of the standard error output
+// of a command kept by WithStderr.
+const maxStderr = 1024
+
+// ExitStatus returns the exit status of the process that ended the
+// *exec.ExitError in err's chain, and whether there is one that exited
+// normally, rather than being killed by a signal.
+func ExitStatus(err error) (int, bool) {
+	var ee *exec.ExitError
+	if !As(err, &ee) || !ee.Exited() {
+		return 0, false
+	}
+	return ee.ExitCode(), true
+}
+
+// WasSignaled returns the signal that killed the process of the
+// *exec.ExitError in err's chain, and whether there is one that was killed
+// by a signal.
+func WasSignaled(err error) (syscall.Signal, bool) {
+	var ee *exec.ExitError
+	if !As(err, &ee) {
+		return 0, false
+	}
+	ws, ok := ee.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return 0, false
+	}
+	return ws.Signal(), true
+}
+
+// WithStderr annotates err with stderr, the standard error output of the
+// command that failed with it, as the detail StderrKey. Only the last
+// kilobyte is kept, as that is where commands report why they failed.
+// If err is nil or stderr is empty, WithStderr returns err.
+func WithStderr(err error, stderr []byte) error {
+	return globalErrorsApi().WithStderr(err, stderr)
+}
+
+// WrapCmd annotates err, returned by running cmd, with a stack trace at the
+// point WrapCmd is called and a message naming the command, and with the
+// end of its standard error output as WithStderr does. The output is taken
+// from the *exec.ExitError in err's chain, which holds it when cmd was run
+// by Output, or from cmd.Stderr if it is a *bytes.Buffer.
+// If err is nil, WrapCmd returns nil.
+func WrapCmd(err error, cmd *exec.Cmd) error {
+	return globalErrorsApi().WrapCmd(err, cmd)
+}
+
+func (e *errorsApi) WithStderr(err error, stderr []byte) error {
+	if err == nil || len(bytes.TrimSpace(stderr)) == 0 {
+		return err
+	}
+	return e.WithDetails(err, StderrKey, stderrSnippet(stderr))
+}
+
+func (e *errorsApi) WrapCmd(err error, cmd *exec.Cmd) error {
+	if err == nil {
+		return nil
+	}
+	var stderr []byte
+	var ee *exec.ExitError
+	if As(err, &ee) && len(ee.Stderr) > 0 {
+		stderr = ee.Stderr
+	} else if buf, ok := cmd.Stderr.(*bytes.Buffer); ok {
+		stderr = buf.Bytes()
+	}
+	return e.WithStderr(e.wrap(1, err, "run "+strings.Join(cmd.Args, " ")), stderr)
+}
+
+// stderrSnippet returns the last maxStderr bytes of stderr, without
+// surrounding white space.
+func stderrSnippet(stderr []byte) string {
+	stderr = bytes.TrimSpace(stderr)
+	if len(stderr) <= maxStderr {
+		return string(stderr)
+	}
+	return "..." + string(stderr[len(stderr)-maxStderr:])
+}
@@ -0,0 +1,68 @@
+//go:build !plan9 && !windows
+// +build !plan9,!windows
+
+package errors
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitStatus(t *testing.T) {
+	_, ok := ExitStatus(io.EOF)
+	assert.False(t, ok)
+
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	status, ok := ExitStatus(Wrap(err, "run"))
+	assert.True(t, ok)
+	assert.Equal(t, 3, status)
+	_, ok = WasSignaled(err)
+	assert.False(t, ok)
+
+	err = exec.Command("sh", "-c", "kill -TERM $$").Run()
+	_, ok = ExitStatus(err)
+	assert.False(t, ok)
+	sig, ok := WasSignaled(Wrap(err, "run"))
+	assert.True(t, ok)
+	assert.Equal(t, syscall.SIGTERM, sig)
+	_, ok = WasSignaled(io.EOF)
+	assert.False(t, ok)
+}
+
+func TestWithStderr(t *testing.T) {
+	assert.Nil(t, WithStderr(nil, []byte("oops")))
+	assert.Same(t, io.EOF, WithStderr(io.EOF, []byte(" \n")))
+
+	err := WithStderr(io.EOF, []byte("oops\n"))
+	assert.Equal(t, "oops", Details(err)[StderrKey])
+
+	long := strings.Repeat("x", 2000) + "the end"
+	got := Details(WithStderr(io.EOF, []byte(long)))[StderrKey].(string)
+	assert.Equal(t, "..."+long[len(long)-1024:], got)
+}
+
+func TestWrapCmd(t *testing.T) {
+	assert.Nil(t, WrapCmd(nil, exec.Command("true")))
+
+	cmd := exec.Command("sh", "-c", "echo no such file >&2; exit 2")
+	_, err := cmd.Output()
+	err = WrapCmd(err, cmd)
+	assert.EqualError(t, err, "run sh -c echo no such file >&2; exit 2: exit status 2")
+	assert.Equal(t, "no such file", Details(err)[StderrKey])
+	assert.Regexp(t, "\ngithub.com/pkg/errors.TestWrapCmd\t.+/github.com/pkg/errors/exec_test.go:\\d+$", fmt.Sprintf("%+v", err))
+	status, _ := ExitStatus(err)
+	assert.Equal(t, 2, status)
+
+	var stderr bytes.Buffer
+	cmd = exec.Command("sh", "-c", "echo denied >&2; exit 1")
+	cmd.Stderr = &stderr
+	err = WrapCmd(cmd.Run(), cmd)
+	assert.Equal(t, "denied", Details(err)[StderrKey])
+}
@@ -0,0 +1,84 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// VerboseEnv is the environment variable that makes HandleMain print the
+// stack traces of the error it handles.
+const VerboseEnv = "ERRORS_VERBOSE"
+
+// stderr and exit are replaced by tests.
+var (
+	stderr io.Writer = os.Stderr
+	exit             = os.Exit
+)
+
+// WithExitCode annotates err with the code a command line program should
+// exit with when err makes it fail.
+// If err is nil, WithExitCode returns nil.
+func WithExitCode(err error, code int) error {
+	return globalErrorsApi().WithExitCode(err, code)
+}
+
+// ExitCode returns the exit code of the outermost error in err's chain
+// that has one. It returns 0 if err is nil and 1 if no error in the chain
+// has an exit code.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	for ; err != nil; err = unwrapOnce(err) {
+		if e, ok := err.(*withExitCode); ok {
+			return e.code
+		}
+	}
+	return 1
+}
+
+type withExitCode struct {
+	annotation
+	code int
+}
+
+func (w *withExitCode) rewrap(cause error) error {
+	c := *w
+	c.cause = cause
+	return &c
+}
+
+func (e *errorsApi) WithExitCode(err error, code int) error {
+	if err == nil {
+		return nil
+	}
+	return &withExitCode{annotation{err}, code}
+}
+
+// HandleMain is meant to be called with the error that ends a command line
+// program. If err is nil, HandleMain does nothing. Otherwise it prints err
+// to stderr, preferring its user message and followed by its hints, and
+// exits with ExitCode(err). If the VerboseEnv environment variable is set
+// to a non-empty value, the stack traces of err are printed as well.
+func HandleMain(err error) {
+	if err == nil {
+		return
+	}
+	printMain(stderr, err, os.Getenv(VerboseEnv) != "")
+	exit(ExitCode(err))
+}
+
+func printMain(w io.Writer, err error, verbose bool) {
+	msg := UserMessage(err)
+	if msg == "" {
+		msg = err.Error()
+	}
+	fmt.Fprintf(w, "Error: %s\n", msg)
+	for _, hint := range Hints(err) {
+		fmt.Fprintf(w, "Hint: %s\n", hint)
+	}
+	if verbose {
+		fmt.Fprintf(w, "\n%+v\n", err)
+	}
+}
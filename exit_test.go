@@ -0,0 +1,51 @@
+package errors
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, ExitCode(nil))
+	assert.Equal(t, 1, ExitCode(io.EOF))
+	assert.Equal(t, 3, ExitCode(WithExitCode(io.EOF, 3)))
+	assert.Equal(t, 3, ExitCode(Wrap(WithExitCode(io.EOF, 3), "wrapped")))
+	assert.Equal(t, 4, ExitCode(WithExitCode(WithExitCode(io.EOF, 3), 4)))
+	assert.Nil(t, WithExitCode(nil, 3))
+}
+
+func TestUserMessageAndHints(t *testing.T) {
+	err := WithHint(WithUserMessage(WithHint(io.EOF, "inner hint"), "the file is empty"), "outer hint")
+	assert.Equal(t, "EOF", err.Error())
+	assert.Equal(t, "the file is empty", UserMessage(err))
+	assert.Equal(t, []string{"outer hint", "inner hint"}, Hints(err))
+	assert.Equal(t, "", UserMessage(io.EOF))
+	assert.Nil(t, Hints(io.EOF))
+	assert.Nil(t, WithHint(nil, "hint"))
+	assert.Nil(t, WithUserMessage(nil, "message"))
+}
+
+func TestHandleMain(t *testing.T) {
+	defer func(w io.Writer, e func(int)) { stderr, exit = w, e }(stderr, exit)
+	var buf bytes.Buffer
+	var code int
+	stderr = &buf
+	exit = func(c int) { code = c }
+
+	HandleMain(nil)
+	assert.Equal(t, "", buf.String())
+
+	t.Setenv(VerboseEnv, "")
+	HandleMain(WithExitCode(WithHint(Wrap(io.EOF, "reading"), "check the file"), 2))
+	assert.Equal(t, "Error: reading: EOF\nHint: check the file\n", buf.String())
+	assert.Equal(t, 2, code)
+
+	buf.Reset()
+	t.Setenv(VerboseEnv, "1")
+	HandleMain(WithUserMessage(New("boom"), "something went wrong"))
+	assert.Regexp(t, "^Error: something went wrong\n\nboom\ngithub.com/pkg/errors.TestHandleMain\t.+\n$", buf.String())
+	assert.Equal(t, 1, code)
+}
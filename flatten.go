@@ -0,0 +1,53 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Flatten returns a single error with the message of err's whole chain,
+// wrapping only the root cause of the chain, so that Is and As still match
+// it. The intermediate wrappers, and their stacks, are dropped. It is meant
+// for errors that are cached, or sent across boundaries that only keep
+// strings. Errors without wrappers are returned as they are.
+// If err is nil, Flatten returns nil.
+func Flatten(err error) error {
+	if err == nil || unwrapOnce(err) == nil {
+		return err
+	}
+	root := RootCause(err)
+	msg := err.Error()
+	if prefix := strings.TrimSuffix(msg, root.Error()); prefix != msg && strings.HasSuffix(prefix, ": ") {
+		return &withMessage{
+			cause: root,
+			msg:   strings.TrimSuffix(prefix, ": "),
+		}
+	}
+	return &flattened{root, msg}
+}
+
+// flattened is returned by Flatten for chains whose message does not end
+// with that of their root cause, as composed by this package. Its message
+// already includes that of its cause.
+type flattened struct {
+	cause error
+	msg   string
+}
+
+func (f *flattened) Error() string { return f.msg }
+func (f *flattened) Cause() error  { return f.cause }
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+func (f *flattened) Unwrap() error { return f.cause }
+
+func (f *flattened) ErrorLineV(level Verbosity) string { return f.msg }
+
+func (f *flattened) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's':
+		io.WriteString(s, f.msg)
+	case 'q':
+		fmt.Fprintf(s, "%q", f.msg)
+	}
+}
@@ -0,0 +1,39 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlatten(t *testing.T) {
+	assert.Nil(t, Flatten(nil))
+	assert.Same(t, io.EOF, Flatten(io.EOF))
+
+	err := WithMessage(WithCode(Wrap(io.EOF, "read"), CodeInternal), "load")
+	flat := Flatten(err)
+	assert.EqualError(t, flat, "load: read: EOF")
+	assert.Same(t, io.EOF, Unwrap(flat))
+	assert.True(t, Is(flat, io.EOF))
+	assert.Equal(t, []string{"load: read", "EOF"}, Lines(flat, true))
+
+	err = fmt.Errorf("read (%w) failed", Wrap(io.EOF, "inner"))
+	flat = Flatten(err)
+	assert.EqualError(t, flat, "read (inner: EOF) failed")
+	assert.Equal(t, "read (inner: EOF) failed", fmt.Sprintf("%+v", flat))
+	assert.True(t, Is(flat, io.EOF))
+	assert.Same(t, io.EOF, Cause(flat))
+}
+
+type sliceError struct{ lines []string }
+
+func (e sliceError) Error() string { return strings.Join(e.lines, "; ") }
+
+func TestFlattenUncomparable(t *testing.T) {
+	err := sliceError{[]string{"a", "b"}}
+	assert.Equal(t, err, Flatten(err))
+	assert.EqualError(t, Flatten(Wrap(err, "load")), "load: a; b")
+}
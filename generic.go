@@ -0,0 +1,48 @@
+package errors
+
+// Wrap1 returns v unchanged together with Wrap(err, message), so that
+//
+//	v, err := f()
+//	return errors.Wrap1(v, err, "f failed")
+//
+// replaces the usual if err != nil block.
+func Wrap1[T any](v T, err error, message string) (T, error) {
+	return v, globalErrorsApi().wrap(0, err, message)
+}
+
+// Wrap1f is like Wrap1, but annotates err as Wrapf does.
+func Wrap1f[T any](v T, err error, format string, args ...interface{}) (T, error) {
+	api := globalErrorsApi()
+	if err == nil && !api.cfg.WrapNilAsNew {
+		return v, nil
+	}
+	return v, api.wrapf(0, err, format, args)
+}
+
+// Wrap2 is like Wrap1 for functions returning two values and an error.
+func Wrap2[T1, T2 any](v1 T1, v2 T2, err error, message string) (T1, T2, error) {
+	return v1, v2, globalErrorsApi().wrap(0, err, message)
+}
+
+// Wrap2f is like Wrap2, but annotates err as Wrapf does.
+func Wrap2f[T1, T2 any](v1 T1, v2 T2, err error, format string, args ...interface{}) (T1, T2, error) {
+	api := globalErrorsApi()
+	if err == nil && !api.cfg.WrapNilAsNew {
+		return v1, v2, nil
+	}
+	return v1, v2, api.wrapf(0, err, format, args)
+}
+
+// Wrap3 is like Wrap1 for functions returning three values and an error.
+func Wrap3[T1, T2, T3 any](v1 T1, v2 T2, v3 T3, err error, message string) (T1, T2, T3, error) {
+	return v1, v2, v3, globalErrorsApi().wrap(0, err, message)
+}
+
+// Wrap3f is like Wrap3, but annotates err as Wrapf does.
+func Wrap3f[T1, T2, T3 any](v1 T1, v2 T2, v3 T3, err error, format string, args ...interface{}) (T1, T2, T3, error) {
+	api := globalErrorsApi()
+	if err == nil && !api.cfg.WrapNilAsNew {
+		return v1, v2, v3, nil
+	}
+	return v1, v2, v3, api.wrapf(0, err, format, args)
+}
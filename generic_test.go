@@ -0,0 +1,63 @@
+package errors
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapN(t *testing.T) {
+	v, err := Wrap1(1, io.EOF, "one")
+	assert.Equal(t, 1, v)
+	assert.EqualError(t, err, "one: EOF")
+	assert.Regexp(t, "^one\ngithub.com/pkg/errors.TestWrapN\t.+/github.com/pkg/errors/generic_test.go:11$", Lines(err, true)[0])
+
+	v, err = Wrap1f(2, io.EOF, "one %d", 1)
+	assert.Equal(t, 2, v)
+	assert.EqualError(t, err, "one 1: EOF")
+	assert.Regexp(t, "^one 1\ngithub.com/pkg/errors.TestWrapN\t.+/github.com/pkg/errors/generic_test.go:16$", Lines(err, true)[0])
+
+	v, err = Wrap1(3, nil, "one")
+	assert.Equal(t, 3, v)
+	assert.NoError(t, err)
+
+	s, b, err := Wrap2("a", true, io.EOF, "two")
+	assert.Equal(t, "a", s)
+	assert.True(t, b)
+	assert.EqualError(t, err, "two: EOF")
+	assert.Regexp(t, "^two\ngithub.com/pkg/errors.TestWrapN\t.+/github.com/pkg/errors/generic_test.go:25$", Lines(err, true)[0])
+
+	_, _, err = Wrap2f("a", true, nil, "two %d", 2)
+	assert.NoError(t, err)
+
+	s, v, b, err = Wrap3("a", 1, true, io.EOF, "three")
+	assert.Equal(t, "a", s)
+	assert.Equal(t, 1, v)
+	assert.True(t, b)
+	assert.EqualError(t, err, "three: EOF")
+	assert.Regexp(t, "^three\ngithub.com/pkg/errors.TestWrapN\t.+/github.com/pkg/errors/generic_test.go:34$", Lines(err, true)[0])
+
+	_, _, _, err = Wrap3f("a", 1, true, io.EOF, "three %d", 3)
+	assert.EqualError(t, err, "three 3: EOF")
+	assert.Regexp(t, "^three 3\ngithub.com/pkg/errors.TestWrapN\t.+/github.com/pkg/errors/generic_test.go:41$", Lines(err, true)[0])
+}
+
+func TestWrapNNilAsNew(t *testing.T) {
+	defer SnapshotGlobalApi()()
+	SetGlobalApi(NewErrorsApi(WithCallerSkip(2), WithWrapNilAsNew(true)))
+
+	_, err := Wrap1(1, nil, "one")
+	assert.EqualError(t, err, "one")
+	_, err = Wrap1f(1, nil, "one %d", 1)
+	assert.EqualError(t, err, "one 1")
+	_, _, err = Wrap2f("a", 1, nil, "two %d", 2)
+	assert.EqualError(t, err, "two 2")
+	_, _, _, err = Wrap3f("a", 1, true, nil, "three %d", 3)
+	assert.EqualError(t, err, "three 3")
+	assert.Equal(t, "github.com/pkg/errors.TestWrapNNilAsNew", nearestStack(err)[0].name())
+
+	SetGlobalApi(nil)
+	_, err = Wrap1f(1, nil, "one %d", 1)
+	assert.NoError(t, err)
+}
@@ -4,22 +4,60 @@ package errors
 
 import (
 	stderrors "errors"
+	"reflect"
 )
 
 // Is reports whether any error in err's chain matches target.
 //
 // The chain consists of err itself followed by the sequence of errors obtained by
-// repeatedly calling Unwrap.
+// repeatedly calling Unwrap, or Cause for errors that do not implement Unwrap,
+// so that errors from libraries that only implement Cause do not break it.
+// Errors implementing Unwrap() []error have every error they return walked.
 //
 // An error is considered to match a target if it is equal to that target or if
 // it implements a method Is(error) bool such that Is(target) returns true.
-func Is(err, target error) bool { return stderrors.Is(err, target) }
+func Is(err, target error) bool {
+	if err == nil || target == nil {
+		return err == target
+	}
+	return is(err, target, reflect.TypeOf(target).Comparable())
+}
+
+func is(err, target error, targetComparable bool) bool {
+	for {
+		if targetComparable && err == target {
+			return true
+		}
+		if x, ok := err.(interface{ Is(error) bool }); ok && x.Is(target) {
+			return true
+		}
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, err := range x.Unwrap() {
+				if err != nil && is(err, target, targetComparable) {
+					return true
+				}
+			}
+			return false
+		case interface{ Cause() error }:
+			err = x.Cause()
+		default:
+			return false
+		}
+		if err == nil {
+			return false
+		}
+	}
+}
 
 // As finds the first error in err's chain that matches target, and if so, sets
 // target to that error value and returns true.
 //
 // The chain consists of err itself followed by the sequence of errors obtained by
-// repeatedly calling Unwrap.
+// repeatedly calling Unwrap, or Cause for errors that do not implement Unwrap,
+// as it does for Is.
 //
 // An error matches target if the error's concrete value is assignable to the value
 // pointed to by target, or if the error has a method As(interface{}) bool such that
@@ -28,7 +66,70 @@ func Is(err, target error) bool { return stderrors.Is(err, target) }
 //
 // As will panic if target is not a non-nil pointer to either a type that implements
 // error, or to any interface type. As returns false if err is nil.
-func As(err error, target interface{}) bool { return stderrors.As(err, target) }
+func As(err error, target interface{}) bool {
+	if err == nil {
+		return false
+	}
+	if target == nil {
+		panic("errors: target cannot be nil")
+	}
+	val := reflect.ValueOf(target)
+	typ := val.Type()
+	if typ.Kind() != reflect.Ptr || val.IsNil() {
+		panic("errors: target must be a non-nil pointer")
+	}
+	targetType := typ.Elem()
+	if targetType.Kind() != reflect.Interface && !targetType.Implements(errorType) {
+		panic("errors: *target must be interface or implement error")
+	}
+	return as(err, target, val, targetType)
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// AsType finds the first error in err's chain that matches the type E, as
+// As does, and if one is found, returns it and true. Otherwise, it returns
+// the zero value of E and false. It mirrors errors.AsType of the standard
+// library, without the pointer As needs:
+//
+//	if perr, ok := errors.AsType[*fs.PathError](err); ok {
+//	        fmt.Println("Failed at path:", perr.Path)
+//	}
+func AsType[E error](err error) (E, bool) {
+	var target E
+	ok := As(err, &target)
+	return target, ok
+}
+
+func as(err error, target interface{}, val reflect.Value, targetType reflect.Type) bool {
+	for {
+		if reflect.TypeOf(err).AssignableTo(targetType) {
+			val.Elem().Set(reflect.ValueOf(err))
+			return true
+		}
+		if x, ok := err.(interface{ As(interface{}) bool }); ok && x.As(target) {
+			return true
+		}
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, err := range x.Unwrap() {
+				if err != nil && as(err, target, val, targetType) {
+					return true
+				}
+			}
+			return false
+		case interface{ Cause() error }:
+			err = x.Cause()
+		default:
+			return false
+		}
+		if err == nil {
+			return false
+		}
+	}
+}
 
 // Unwrap returns the result of calling the Unwrap method on err, if err's
 // type contains an Unwrap method returning error.
@@ -61,6 +61,30 @@ func TestIs(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "cause only wrapper",
+			args: args{
+				err:    fmt.Errorf("wrap it: %w", causeOnly{err}),
+				target: err,
+			},
+			want: true,
+		},
+		{
+			name: "joined",
+			args: args{
+				err:    Join(New("other"), causeOnly{err}),
+				target: err,
+			},
+			want: true,
+		},
+		{
+			name: "no match",
+			args: args{
+				err:    causeOnly{New("test")},
+				target: err,
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -121,6 +145,14 @@ func TestAs(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "cause only wrapper",
+			args: args{
+				err:    fmt.Errorf("wrap it: %w", causeOnly{WithStack(err)}),
+				target: new(customErr),
+			},
+			want: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -176,3 +208,24 @@ func TestUnwrap(t *testing.T) {
 		})
 	}
 }
+
+type asTypeError struct{ msg string }
+
+func (e *asTypeError) Error() string { return e.msg }
+
+func TestAsType(t *testing.T) {
+	err := Wrap(&asTypeError{"inner"}, "outer")
+	if e, ok := AsType[*asTypeError](err); !ok || e.msg != "inner" {
+		t.Errorf("AsType[*asTypeError](%v) = %v, %v, want inner, true", err, e, ok)
+	}
+	if e, ok := AsType[*asTypeError](New("foo")); ok || e != nil {
+		t.Errorf("AsType[*asTypeError](New) = %v, %v, want nil, false", e, ok)
+	}
+	type causer interface {
+		error
+		Cause() error
+	}
+	if c, ok := AsType[causer](err); !ok || c != err {
+		t.Errorf("AsType[causer](%v) = %v, %v, want the error itself, true", err, c, ok)
+	}
+}
@@ -0,0 +1,100 @@
+package errors
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Hook is called with every error an api creates, together with the frame
+// the error was created at, or the zero Frame if the error has no stack.
+// Hooks run synchronously on the goroutine creating the error, so they
+// should be cheap; they are meant for auditing and metrics.
+type Hook func(err error, frame Frame)
+
+// Hooks holds the hooks of an api.
+type Hooks struct {
+	// OnNew hooks are called with the errors created by New, Errorf,
+	// NewPlain and the coded constructors.
+	OnNew []Hook
+	// OnWrap hooks are called with the wrappers created by Wrap, Wrapf,
+	// WithStack, WithMessage and the other functions that annotate an error
+	// with a message or a stack.
+	OnWrap []Hook
+}
+
+// WithHooksOpt adds hooks to the api being configured.
+func WithHooksOpt(hooks Hooks) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.Hooks.OnNew = append(c.Hooks.OnNew[:len(c.Hooks.OnNew):len(c.Hooks.OnNew)], hooks.OnNew...)
+		c.Hooks.OnWrap = append(c.Hooks.OnWrap[:len(c.Hooks.OnWrap):len(c.Hooks.OnWrap)], hooks.OnWrap...)
+	})
+}
+
+// OnNew registers hook to be called with every error e creates, and every
+// error created by the apis derived from e.
+func (e *errorsApi) OnNew(hook Hook) {
+	e.hooks.add(&e.hooks.onNew, hook)
+}
+
+// OnWrap registers hook to be called with every wrapper e creates, and
+// every wrapper created by the apis derived from e.
+func (e *errorsApi) OnWrap(hook Hook) {
+	e.hooks.add(&e.hooks.onWrap, hook)
+}
+
+// apiHooks holds the hooks registered on an api. The hooks of the api it
+// was derived from, if any, are reached through parent and run after its
+// own. The hook slices are replaced as a whole on every registration, so
+// that errors can be created while hooks are added.
+type apiHooks struct {
+	parent *apiHooks
+	mu     sync.Mutex
+	onNew  atomic.Value // []Hook
+	onWrap atomic.Value // []Hook
+}
+
+func (h *apiHooks) add(hooks *atomic.Value, hook Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	old, _ := hooks.Load().([]Hook)
+	hooks.Store(append(old[:len(old):len(old)], hook))
+}
+
+// created annotates err, whose stack is st, with the default details if e
+// is the global api, counts it for Stats and runs the OnNew hooks of e for
+// the result.
+func (e *errorsApi) created(err error, st *stack) error {
+	err = e.withDefaultDetails(err)
+	if statsEnabled() {
+		countError(err, true)
+	}
+	for h := e.hooks; h != nil; h = h.parent {
+		runHooks(&h.onNew, err, st)
+	}
+	return err
+}
+
+// wrapped counts err, whose stack is st, for Stats and runs the OnWrap
+// hooks of e for it.
+func (e *errorsApi) wrapped(err error, st *stack) {
+	if statsEnabled() {
+		countError(err, false)
+	}
+	for h := e.hooks; h != nil; h = h.parent {
+		runHooks(&h.onWrap, err, st)
+	}
+}
+
+func runHooks(hooks *atomic.Value, err error, st *stack) {
+	hs, _ := hooks.Load().([]Hook)
+	if len(hs) == 0 {
+		return
+	}
+	var frame Frame
+	if st != nil && len(*st) > 0 {
+		frame = Frame((*st)[0])
+	}
+	for _, hook := range hs {
+		hook(err, frame)
+	}
+}
@@ -0,0 +1,54 @@
+package errors
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type hookRecorder struct {
+	errs   []error
+	frames []string
+}
+
+func (r *hookRecorder) hook(err error, frame Frame) {
+	r.errs = append(r.errs, err)
+	r.frames = append(r.frames, frame.name())
+}
+
+func TestHooks(t *testing.T) {
+	var news, wraps hookRecorder
+	api := NewErrorsApi(WithHooksOpt(Hooks{OnNew: []Hook{news.hook}}))
+	api.OnWrap(wraps.hook)
+
+	err := api.New("foo")
+	wrapped := api.Wrap(err, "bar")
+	plain := api.WithMessage(wrapped, "baz")
+	coded := api.NotFoundf("missing")
+	assert.Equal(t, []error{err, coded}, news.errs)
+	assert.Equal(t, []string{"github.com/pkg/errors.TestHooks", "github.com/pkg/errors.TestHooks"}, news.frames)
+	assert.Equal(t, []error{wrapped, plain}, wraps.errs)
+	assert.Equal(t, []string{"github.com/pkg/errors.TestHooks", "unknown"}, wraps.frames)
+
+	// Stack-free apis do not create a wrapper, and so do not run hooks,
+	// for WithStack.
+	wraps = hookRecorder{}
+	api.Derive(WithDisableStack(true)).WithStack(io.EOF)
+	assert.Empty(t, wraps.errs)
+}
+
+func TestDeriveHooks(t *testing.T) {
+	var parent, child hookRecorder
+	api := NewErrorsApi()
+	derived := api.Derive(WithHooksOpt(Hooks{OnNew: []Hook{child.hook}}))
+	api.OnNew(parent.hook)
+
+	err := derived.New("derived")
+	assert.Equal(t, []error{err}, child.errs)
+	assert.Equal(t, []error{err}, parent.errs)
+
+	err = api.New("parent")
+	assert.Len(t, child.errs, 1)
+	assert.Equal(t, err, parent.errs[1])
+}
@@ -0,0 +1,37 @@
+//go:build go1.23
+// +build go1.23
+
+package errors
+
+import "iter"
+
+// All returns an iterator over every error in err's chain, starting with
+// err itself, in the order Chain returns them:
+//
+//	for e := range errors.All(err) {
+//	        if t, ok := e.(interface{ Temporary() bool }); ok && t.Temporary() {
+//	                return true
+//	        }
+//	}
+//
+// Errors that join several others end the chain; see AllTree.
+func All(err error) iter.Seq[error] {
+	return func(yield func(error) bool) {
+		for ; err != nil; err = unwrapOnce(err) {
+			if !yield(err) {
+				return
+			}
+		}
+	}
+}
+
+// AllTree returns an iterator over err and every error reachable from it,
+// in the depth-first order of Walk, so that the children of joined errors
+// are visited as well.
+func AllTree(err error) iter.Seq[error] {
+	return func(yield func(error) bool) {
+		Walk(err, func(err error, _ int) bool {
+			return yield(err)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+//go:build go1.23
+// +build go1.23
+
+package errors
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAll(t *testing.T) {
+	for range All(nil) {
+		t.Fatal("All(nil) yielded an error")
+	}
+
+	err := WithMessage(Wrap(io.EOF, "read"), "load")
+	var got []error
+	for e := range All(err) {
+		got = append(got, e)
+	}
+	assert.Equal(t, Chain(err), got)
+
+	got = got[:0]
+	for e := range All(err) {
+		got = append(got, e)
+		break
+	}
+	assert.Equal(t, []error{err}, got)
+}
+
+func TestAllTree(t *testing.T) {
+	a, b := Wrap(io.EOF, "a"), io.ErrUnexpectedEOF
+	err := WithMessage(Join(a, b), "both")
+
+	var got []error
+	for e := range AllTree(err) {
+		got = append(got, e)
+	}
+	assert.Equal(t, []error{err, unwrapOnce(err), a, io.EOF, b}, got)
+
+	got = got[:0]
+	for e := range AllTree(err) {
+		if e == a {
+			break
+		}
+		got = append(got, e)
+	}
+	assert.Len(t, got, 2)
+}
@@ -0,0 +1,111 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+)
+
+// Join returns an error that wraps the given errors. Any nil error values
+// are discarded. Join returns nil if every value in errs is nil. The error
+// formats as the messages of the wrapped errors, separated by newlines,
+// and implements Unwrap() []error, as the errors returned by the standard
+// library's Join do.
+func Join(errs ...error) error {
+	return globalErrorsApi().Join(errs...)
+}
+
+func (e *errorsApi) Join(errs ...error) error {
+	n := 0
+	for _, err := range errs {
+		if err != nil {
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	j := &joinError{
+		errs:   make([]error, 0, n),
+		format: e.format,
+	}
+	for _, err := range errs {
+		if err != nil {
+			j.errs = append(j.errs, err)
+		}
+	}
+	return j
+}
+
+type joinError struct {
+	errs []error
+	// format holds the options of the api that built the join, nil for
+	// the global one.
+	format *Config
+}
+
+func (e *joinError) Error() string {
+	var b []byte
+	for i, err := range e.errs {
+		if i > 0 {
+			b = append(b, '\n')
+		}
+		b = append(b, err.Error()...)
+	}
+	return string(b)
+}
+
+func (e *joinError) Unwrap() []error {
+	return e.errs
+}
+
+func (e *joinError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			for i, err := range e.errs {
+				if i > 0 {
+					io.WriteString(s, formatOptions(e.format).StackSep)
+				}
+				formatCause(s, err)
+			}
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+// FirstError returns the first error in errs that is not nil, or nil if
+// there is none.
+func FirstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Coalesce returns nil if every error in errs is nil, the only non-nil
+// error if there is exactly one, and the Join of the non-nil errors
+// otherwise.
+func Coalesce(errs ...error) error {
+	return globalErrorsApi().Coalesce(errs...)
+}
+
+func (e *errorsApi) Coalesce(errs ...error) error {
+	var first error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if first != nil {
+			return e.Join(errs...)
+		}
+		first = err
+	}
+	return first
+}
@@ -0,0 +1,39 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoin(t *testing.T) {
+	assert.Nil(t, Join())
+	assert.Nil(t, Join(nil, nil))
+
+	a, b := New("a"), io.EOF
+	err := Join(a, nil, b)
+	assert.Equal(t, "a\nEOF", err.Error())
+	assert.Equal(t, []error{a, b}, err.(interface{ Unwrap() []error }).Unwrap())
+	assert.True(t, Is(err, io.EOF))
+	assert.Equal(t, "a\nEOF", fmt.Sprintf("%v", err))
+	assert.Equal(t, `"a\nEOF"`, fmt.Sprintf("%q", err))
+	assert.Regexp(t, "^a\ngithub.com/pkg/errors.TestJoin\t.+\nEOF$", fmt.Sprintf("%+v", err))
+}
+
+func TestFirstError(t *testing.T) {
+	assert.Nil(t, FirstError())
+	assert.Nil(t, FirstError(nil, nil))
+	assert.Same(t, io.EOF, FirstError(nil, io.EOF, io.ErrUnexpectedEOF))
+}
+
+func TestCoalesce(t *testing.T) {
+	assert.Nil(t, Coalesce())
+	assert.Nil(t, Coalesce(nil, nil))
+	assert.Same(t, io.EOF, Coalesce(nil, io.EOF, nil))
+
+	err := Coalesce(nil, io.EOF, nil, io.ErrUnexpectedEOF)
+	assert.Equal(t, "EOF\nunexpected EOF", err.Error())
+	assert.True(t, Is(err, io.ErrUnexpectedEOF))
+}
@@ -34,10 +34,10 @@ func TestFrameMarshalJSON(t *testing.T) {
 		want string
 	}{{
 		initpc,
-		`^"github\.com/pkg/errors\.init(\.ializers)? .+/github\.com/pkg/errors/stack_test.go:\d+"$`,
+		`^{"function":"github\.com/pkg/errors\.init(\.ializers)?","file":".+/github\.com/pkg/errors/stack_test.go","line":\d+}$`,
 	}, {
 		0,
-		`^"unknown"$`,
+		`^{"function":"unknown"}$`,
 	}}
 	for i, tt := range tests {
 		got, err := json.Marshal(tt.Frame)
@@ -49,3 +49,14 @@ func TestFrameMarshalJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestStackTraceMarshalJSON(t *testing.T) {
+	got, err := json.Marshal(StackTrace{initpc, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `^\[{"function":"github\.com/pkg/errors\.init(\.ializers)?","file":".+/github\.com/pkg/errors/stack_test.go","line":\d+},{"function":"unknown"}\]$`
+	if !regexp.MustCompile(want).Match(got) {
+		t.Errorf("MarshalJSON:\n got %q\n want %q", string(got), want)
+	}
+}
@@ -0,0 +1,64 @@
+package errors
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DomainKey is the detail key WithDomain stores domains under.
+const DomainKey = "errors.domain"
+
+// WithDomain annotates err with the domain it belongs to, such as the
+// service or subsystem that raised it, as the detail DomainKey.
+// If err is nil, WithDomain returns nil.
+func WithDomain(err error, domain string) error {
+	return globalErrorsApi().WithDomain(err, domain)
+}
+
+// Domain returns the domain attached to err's chain by WithDomain, and
+// whether there was one. If several were attached, the outermost wins.
+func Domain(err error) (string, bool) {
+	for ; err != nil; err = unwrapOnce(err) {
+		d, ok := err.(*withDetails)
+		if !ok {
+			continue
+		}
+		for _, kv := range d.details {
+			if kv.key != DomainKey {
+				continue
+			}
+			if domain, ok := kv.get().(string); ok {
+				return domain, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (e *errorsApi) WithDomain(err error, domain string) error {
+	return e.WithDetails(err, DomainKey, domain)
+}
+
+// Labels returns labels describing err for tagging metrics. They all have
+// few possible values, so they can be used with any metrics system:
+//
+//	code       the name of Code(err)
+//	domain     the domain attached by WithDomain, if any
+//	retryable  "true" or "false", as reported by IsRetryable
+//	root_type  the Go type of RootCause(err), as in "syscall.Errno"
+//
+// Labels returns nil if err is nil.
+func Labels(err error) map[string]string {
+	if err == nil {
+		return nil
+	}
+	labels := map[string]string{
+		"code":      Code(err).String(),
+		"retryable": strconv.FormatBool(IsRetryable(err)),
+		"root_type": fmt.Sprintf("%T", RootCause(err)),
+	}
+	if domain, ok := Domain(err); ok {
+		labels["domain"] = domain
+	}
+	return labels
+}
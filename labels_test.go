@@ -0,0 +1,46 @@
+package errors
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomain(t *testing.T) {
+	assert.Nil(t, WithDomain(nil, "billing"))
+	_, ok := Domain(io.EOF)
+	assert.False(t, ok)
+
+	err := WithDomain(Wrap(WithDomain(io.EOF, "storage"), "read"), "billing")
+	assert.EqualError(t, err, "read: EOF")
+	domain, ok := Domain(err)
+	assert.True(t, ok)
+	assert.Equal(t, "billing", domain)
+
+	called := false
+	lazy := Lazy(func() interface{} { called = true; return "alice" })
+	domain, _ = Domain(WithDetails(err, "user", lazy))
+	assert.Equal(t, "billing", domain)
+	assert.False(t, called)
+}
+
+func TestLabels(t *testing.T) {
+	assert.Nil(t, Labels(nil))
+	assert.Equal(t, map[string]string{
+		"code":      "Unknown",
+		"retryable": "false",
+		"root_type": "*errors.errorString",
+	}, Labels(io.EOF))
+
+	_, err := os.Open("/does/not/exist")
+	err = WithDomain(WithRetryable(WithCode(Wrap(err, "open"), CodeNotFound), true), "storage")
+	assert.Equal(t, map[string]string{
+		"code":      "NotFound",
+		"domain":    "storage",
+		"retryable": "true",
+		"root_type": "syscall.Errno",
+	}, Labels(err))
+	assert.Equal(t, "*errors.fundamental", Labels(New("foo"))["root_type"])
+}
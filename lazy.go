@@ -0,0 +1,115 @@
+package errors
+
+import (
+	"fmt"
+	"sync"
+)
+
+// NewLazy is like New, but the message is only computed, by calling
+// message, the first time it is needed, as by Error or when the error is
+// formatted. It is meant for hot paths that build detailed messages for
+// errors that are usually handled and discarded. message is called at most
+// once, and may be called from any goroutine.
+func NewLazy(message func() string) error {
+	return globalErrorsApi().NewLazy(message)
+}
+
+// WithLazyMessage is like WithMessage, but the message is only computed, by
+// calling message, the first time it is needed, as NewLazy does.
+// If err is nil, WithLazyMessage returns nil.
+func WithLazyMessage(err error, message func() string) error {
+	return globalErrorsApi().WithLazyMessage(err, message)
+}
+
+func (e *errorsApi) NewLazy(message func() string) error {
+	st := e.callers(0)
+	l := &lazyError{
+		stack:   st,
+		format:  e.format,
+		message: message,
+		build: func(msg string) error {
+			return e.fundamental(msg, st, nil)
+		},
+	}
+	return e.created(l, st)
+}
+
+func (e *errorsApi) WithLazyMessage(err error, message func() string) error {
+	if err == nil {
+		return nil
+	}
+	if c, ok := e.collapse(err); ok {
+		return c
+	}
+	l := &lazyError{
+		cause:   err,
+		format:  e.format,
+		message: message,
+		build: func(msg string) error {
+			w := &withMessage{
+				cause:  err,
+				msg:    e.dedupMessage(err, msg),
+				format: e.format,
+				render: e.render,
+			}
+			e.cacheError(w)
+			return w
+		},
+	}
+	e.wrapped(l, nil)
+	return l
+}
+
+// lazyError is the error returned by NewLazy and WithLazyMessage. Once its
+// message is needed, it builds the error NewPlain or WithMessage would have
+// returned, with the stack of the lazy error, and delegates to it.
+type lazyError struct {
+	cause   error
+	stack   *stack
+	format  *Config
+	message func() string
+	build   func(msg string) error
+
+	once sync.Once
+	err  error
+}
+
+// get returns the error l delegates to, building it on the first call.
+func (l *lazyError) get() error {
+	l.once.Do(func() {
+		l.err = l.build(l.message())
+		l.message, l.build = nil, nil
+	})
+	return l.err
+}
+
+func (l *lazyError) Error() string { return l.get().Error() }
+
+func (l *lazyError) layerMessage() string { return l.get().(layer).layerMessage() }
+func (l *lazyError) layerStack() *stack   { return l.stack }
+func (l *lazyError) layerFormat() *Config { return l.format }
+
+func (l *lazyError) Cause() error  { return l.cause }
+func (l *lazyError) Unwrap() error { return l.cause }
+
+func (l *lazyError) StackTrace() StackTrace {
+	if l.stack != nil {
+		return l.stack.StackTrace()
+	}
+	return nearestStack(l.cause)
+}
+
+func (l *lazyError) Format(s fmt.State, verb rune) {
+	l.get().(fmt.Formatter).Format(s, verb)
+}
+
+func (l *lazyError) ErrorLineV(level Verbosity) string {
+	return l.get().(Liner).ErrorLineV(level)
+}
+
+func (l *lazyError) rewrap(cause error) error {
+	if r, ok := l.get().(rewrapper); ok {
+		return r.rewrap(cause)
+	}
+	return l
+}
@@ -0,0 +1,50 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLazy(t *testing.T) {
+	calls := 0
+	err := NewLazy(func() string {
+		calls++
+		return "expensive"
+	})
+	assert.Equal(t, 0, calls)
+	assert.NotNil(t, TopFrames(err, 1))
+	assert.Equal(t, 0, calls)
+
+	assert.EqualError(t, err, "expensive")
+	assert.Regexp(t, "^expensive\ngithub.com/pkg/errors.TestNewLazy\t.+/github.com/pkg/errors/lazy_test.go:13$", fmt.Sprintf("%+v", err))
+	assert.Equal(t, "expensive", fmt.Sprintf("%v", err))
+	assert.EqualError(t, Wrap(err, "read"), "read: expensive")
+	assert.Equal(t, 1, calls)
+}
+
+func TestWithLazyMessage(t *testing.T) {
+	assert.Nil(t, WithLazyMessage(nil, func() string { return "never" }))
+
+	calls := 0
+	cause := New("cause")
+	err := WithLazyMessage(cause, func() string {
+		calls++
+		return "lazy"
+	})
+	assert.Same(t, cause, Cause(err))
+	assert.Same(t, cause, Unwrap(err))
+	assert.Equal(t, 0, calls)
+
+	err = WithCode(err, CodeInternal)
+	assert.EqualError(t, err, "lazy: cause")
+	assert.Equal(t, []string{"lazy", "cause"}, Lines(err, false))
+	assert.Regexp(t, "^cause\ngithub.com/pkg/errors.TestWithLazyMessage\t.+\nlazy$", fmt.Sprintf("%+v", err))
+	assert.Equal(t, 1, calls)
+
+	assert.EqualError(t, WithoutStack(err), "lazy: cause")
+	assert.EqualError(t, ReplaceCause(err, io.EOF), "lazy: EOF")
+	assert.EqualError(t, Rewrap(err, func(msg string) string { return "<" + msg + ">" }), "<lazy>: <cause>")
+}
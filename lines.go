@@ -1,23 +1,468 @@
 package errors
 
-type liner interface {
-	errorLine(stack bool) string
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Verbosity selects how much of a layer a Liner renders.
+type Verbosity int
+
+const (
+	// VerbosityTerse asks for the shortest useful form of a layer.
+	VerbosityTerse Verbosity = iota
+	// VerbosityNormal asks for the layer's message, as Lines(err, false) does.
+	VerbosityNormal
+	// VerbosityDebug asks for the message together with any debugging
+	// information, such as the stack trace, as Lines(err, true) does.
+	VerbosityDebug
+)
+
+// Liner is implemented by errors that know how to render their own layer of
+// an error chain. Lines and the %+v verb use it in place of Error(), so
+// third-party error types can provide terse, normal and debug forms.
+// An empty line means the layer has nothing to show at that verbosity.
+type Liner interface {
+	ErrorLineV(level Verbosity) string
+}
+
+// EmptyLayers selects what LinesWith does with layers that render as an
+// empty line, such as WithStack wrappers when no stack is requested.
+type EmptyLayers int
+
+const (
+	// EmptySkip leaves empty layers out of the result.
+	EmptySkip EmptyLayers = iota
+	// EmptyKeep keeps empty layers as empty strings, so that the result has
+	// exactly one entry per layer of the chain.
+	EmptyKeep
+	// EmptyPlaceholder replaces empty layers by a placeholder describing
+	// what they carry, such as "[code=NotFound]", so that the result has
+	// exactly one entry per layer of the chain.
+	EmptyPlaceholder
+	// EmptyMerge prefixes the placeholders of empty layers to the next
+	// line that is not empty, as in "[code=NotFound] read user", so that
+	// no information is lost without adding lines.
+	EmptyMerge
+)
+
+type lineConfig struct {
+	level         Verbosity
+	frames        int
+	msgSep        string
+	stackSep      string
+	funcSep       string
+	empty         EmptyLayers
+	trim          []string
+	deterministic bool
+	dedup         bool
+	group         []layer
+	pending       []string
+
+	// opts are the options c was created with, and formats the configs
+	// derived from c for the layers that carry the format of their api.
+	opts    []LineOption
+	formats map[*Config]*lineConfig
+}
+
+// LineOption configures a single call to LinesWith.
+type LineOption func(*lineConfig)
+
+// LineVerbosity sets the verbosity every layer is rendered at.
+// The default is VerbosityNormal.
+func LineVerbosity(level Verbosity) LineOption {
+	return func(c *lineConfig) {
+		c.level = level
+	}
+}
+
+// LineFrames limits the number of stack frames rendered per layer.
+// Zero, the default, renders every captured frame.
+func LineFrames(n int) LineOption {
+	return func(c *lineConfig) {
+		c.frames = n
+	}
 }
 
+// LineSeparators overrides the separators placed between a message and its
+// stack, between stack frames, and between a frame's function and file.
+func LineSeparators(msgSep, stackSep, funcSep string) LineOption {
+	return func(c *lineConfig) {
+		c.msgSep = msgSep
+		c.stackSep = stackSep
+		c.funcSep = funcSep
+	}
+}
+
+// lineFormat takes the separators from opts, the options of the api that
+// created the error being rendered, if it has any.
+func lineFormat(opts *Config) LineOption {
+	return func(c *lineConfig) {
+		if opts != nil {
+			c.msgSep = opts.MsgSep
+			c.stackSep = opts.StackSep
+			c.funcSep = opts.FuncSep
+			c.trim = opts.TrimPrefixes
+			c.deterministic = opts.Deterministic
+		}
+	}
+}
+
+// LineEmptyLayers selects how layers that render as empty lines are handled.
+func LineEmptyLayers(mode EmptyLayers) LineOption {
+	return func(c *lineConfig) {
+		c.empty = mode
+	}
+}
+
+// LineTrimPrefix strips the first matching prefix from the file name of
+// every rendered frame, in addition to the TrimPrefixes of the options.
+func LineTrimPrefix(prefixes ...string) LineOption {
+	return func(c *lineConfig) {
+		c.trim = append(c.trim[:len(c.trim):len(c.trim)], prefixes...)
+	}
+}
+
+// LineDedup collapses consecutive layers with identical messages, as
+// produced by retry loops that wrap with the same text, into a single line
+// carrying a repeat count and only the distinct stacks of those layers.
+func LineDedup(dedup bool) LineOption {
+	return func(c *lineConfig) {
+		c.dedup = dedup
+	}
+}
+
+// Lines returns one line per layer of err's chain, outermost first.
+// If stack is true, lines include the stack trace of their layer.
 func Lines(err error, stack bool) []string {
-	var errors = []string{}
-	for err != nil {
-		var line string
+	if stack {
+		return LinesV(err, VerbosityDebug)
+	}
+	return LinesV(err, VerbosityNormal)
+}
+
+// Messages returns the message of every layer of err's chain, outermost
+// first, as Lines(err, false) does, but without setting up any formatting,
+// so it is cheaper on hot logging paths.
+func Messages(err error) []string {
+	var messages = []string{}
+	for ; err != nil; err = Unwrap(err) {
+		var msg string
 		switch err := err.(type) {
-		case liner:
-			line = err.errorLine(stack)
+		case layer:
+			msg = err.layerMessage()
+		case Liner:
+			msg = err.ErrorLineV(VerbosityNormal)
 		default:
-			line = err.Error()
+			msg = err.Error()
 		}
-		if len(line) != 0 {
-			errors = append(errors, line)
+		if msg != "" {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// LinesV is like Lines, but lets the caller pick the verbosity every layer
+// is rendered at.
+func LinesV(err error, level Verbosity) []string {
+	return LinesWith(err, LineVerbosity(level))
+}
+
+// LinesWith is like Lines, but is configured by opts instead of the package
+// level options set by SetOptions. Separators that are not overridden are
+// taken from the options of the api that created each layer, if it has
+// any, and from the package level options at the time of the call
+// otherwise.
+func LinesWith(err error, opts ...LineOption) []string {
+	c := newLineConfig(opts...)
+	var errors = []string{}
+	var buf strings.Builder
+	for err != nil {
+		buf.Reset()
+		if c.writeNextOrEmpty(&buf, &err) {
+			errors = append(errors, buf.String())
 		}
-		err = Unwrap(err)
+	}
+	if len(c.pending) > 0 {
+		buf.Reset()
+		c.writePending(&buf)
+		errors = append(errors, buf.String())
 	}
 	return errors
 }
+
+// WriteLines writes the lines Lines would return to w, each followed by a
+// newline, without building them all in memory first.
+func WriteLines(w io.Writer, err error, stack bool) error {
+	if stack {
+		return WriteLinesWith(w, err, LineVerbosity(VerbosityDebug))
+	}
+	return WriteLinesWith(w, err)
+}
+
+// WriteLinesWith is the streaming counterpart of LinesWith.
+func WriteLinesWith(w io.Writer, err error, opts ...LineOption) error {
+	c := newLineConfig(opts...)
+	bw := bufio.NewWriter(w)
+	for err != nil {
+		if c.writeNextOrEmpty(bw, &err) {
+			bw.WriteByte('\n')
+		}
+	}
+	if len(c.pending) > 0 {
+		c.writePending(bw)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+func newLineConfig(opts ...LineOption) *lineConfig {
+	o := options()
+	c := &lineConfig{
+		level:         VerbosityNormal,
+		msgSep:        o.MsgSep,
+		stackSep:      o.StackSep,
+		funcSep:       o.FuncSep,
+		trim:          o.TrimPrefixes,
+		deterministic: o.Deterministic,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.opts = opts
+	return c
+}
+
+// layerConfig returns the config to render a layer created with the format
+// f with: c with the separators and prefixes of f, which the options c was
+// created with still override. It returns c itself if f is nil.
+func (c *lineConfig) layerConfig(f *Config) *lineConfig {
+	if f == nil {
+		return c
+	}
+	if lc, ok := c.formats[f]; ok {
+		return lc
+	}
+	lc := newLineConfig(append([]LineOption{lineFormat(f)}, c.opts...)...)
+	if c.formats == nil {
+		c.formats = make(map[*Config]*lineConfig)
+	}
+	c.formats[f] = lc
+	return lc
+}
+
+// lineWriter is satisfied by both *strings.Builder and *bufio.Writer.
+type lineWriter interface {
+	io.StringWriter
+	io.ByteWriter
+}
+
+// writeNextOrEmpty is like writeNext, but handles empty layers as c.empty
+// selects. It reports whether a line was completed.
+func (c *lineConfig) writeNextOrEmpty(w lineWriter, err *error) bool {
+	if c.empty != EmptyMerge {
+		cur := *err
+		if c.writeNext(w, err) {
+			return true
+		}
+		switch c.empty {
+		case EmptyKeep:
+			return true
+		case EmptyPlaceholder:
+			w.WriteString("[" + describeLayer(cur) + "]")
+			return true
+		}
+		return false
+	}
+
+	// The line is written to a scratch buffer, as the placeholders of the
+	// layers before it must only be written if it is not empty.
+	cur := *err
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if !c.writeNext(buf, err) {
+		c.pending = append(c.pending, describeLayer(cur))
+		return false
+	}
+	if len(c.pending) > 0 {
+		c.writePending(w)
+		w.WriteByte(' ')
+	}
+	w.WriteString(buf.String())
+	return true
+}
+
+// writePending writes the placeholders of the empty layers merged by
+// EmptyMerge into the next line to w, and forgets them.
+func (c *lineConfig) writePending(w lineWriter) {
+	w.WriteString("[" + strings.Join(c.pending, " ") + "]")
+	c.pending = c.pending[:0]
+}
+
+// describeLayer returns the information carried by the layer err, which
+// renders as an empty line, for the placeholders of EmptyPlaceholder and
+// EmptyMerge.
+func describeLayer(err error) string {
+	switch err := err.(type) {
+	case *withCode:
+		return "code=" + err.code.String()
+	case *withUserMessage:
+		return "user_message=" + strconv.Quote(err.msg)
+	case *withHint:
+		return "hint=" + strconv.Quote(err.hint)
+	case *withExitCode:
+		return "exit_code=" + strconv.Itoa(err.code)
+	case *withResource:
+		return "resource=" + err.resource.Kind + "/" + err.resource.ID
+	case *withTime:
+		return "time=" + err.time.Format(time.RFC3339Nano)
+	case *withRetryable:
+		return "retryable=" + strconv.FormatBool(err.retryable)
+	case *withRetryAfter:
+		return "retry_after=" + err.delay.String()
+	case *withStopped:
+		return "stopped=" + strconv.Quote(err.stopped.Error())
+	case *withDetails:
+		var b strings.Builder
+		for i, kv := range err.details {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%s=%v", kv.key, kv.get())
+		}
+		return b.String()
+	case *withStack:
+		return "stack"
+	case *PanicError:
+		return "panic"
+	}
+	return fmt.Sprintf("%T", err)
+}
+
+// writeNext writes the next line of the chain to w, advances *err past the
+// layers it covered and reports whether anything was written.
+func (c *lineConfig) writeNext(w lineWriter, err *error) bool {
+	l, ok := (*err).(layer)
+	if !c.dedup || !ok || l.layerMessage() == "" {
+		written := c.writeLine(w, *err)
+		*err = Unwrap(*err)
+		return written
+	}
+
+	c.group = append(c.group[:0], l)
+	*err = Unwrap(*err)
+	for *err != nil {
+		next, ok := (*err).(layer)
+		if !ok || next.layerMessage() != l.layerMessage() {
+			break
+		}
+		c.group = append(c.group, next)
+		*err = Unwrap(*err)
+	}
+	if len(c.group) == 1 {
+		return c.layerConfig(l.layerFormat()).writeLayer(w, l.layerMessage(), l.layerStack())
+	}
+
+	w.WriteString(l.layerMessage())
+	w.WriteString(" (repeated ")
+	w.WriteString(strconv.Itoa(len(c.group)))
+	w.WriteString(" times)")
+	if c.level < VerbosityDebug {
+		return true
+	}
+	sep := c.layerConfig(l.layerFormat()).msgSep
+	for i, g := range c.group {
+		st := g.layerStack()
+		if st == nil || len(*st) == 0 || c.seen(st, i) {
+			continue
+		}
+		gc := c.layerConfig(g.layerFormat())
+		w.WriteString(sep)
+		gc.writeLayer(w, "", st)
+		sep = gc.stackSep
+	}
+	return true
+}
+
+// seen reports whether a layer of the current group before index i
+// captured the same stack as st.
+func (c *lineConfig) seen(st *stack, i int) bool {
+	for _, g := range c.group[:i] {
+		if prev := g.layerStack(); prev != nil && equalStacks(*prev, *st) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalStacks(a, b stack) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// line renders the outermost layer of err.
+func (c *lineConfig) line(err error) string {
+	var buf strings.Builder
+	c.writeLine(&buf, err)
+	return buf.String()
+}
+
+// writeLine writes the outermost layer of err to w and reports whether
+// anything was written.
+func (c *lineConfig) writeLine(w lineWriter, err error) bool {
+	var line string
+	switch err := err.(type) {
+	case layer:
+		return c.layerConfig(err.layerFormat()).writeLayer(w, err.layerMessage(), err.layerStack())
+	case Liner:
+		line = err.ErrorLineV(c.level)
+	default:
+		line = err.Error()
+	}
+	w.WriteString(line)
+	return line != ""
+}
+
+func (c *lineConfig) writeLayer(w lineWriter, msg string, st *stack) bool {
+	w.WriteString(msg)
+	if c.level < VerbosityDebug || st == nil || len(*st) == 0 {
+		return msg != ""
+	}
+	if msg != "" {
+		w.WriteString(c.msgSep)
+	}
+	for i, pc := range *st {
+		if c.frames > 0 && i >= c.frames {
+			break
+		}
+		if i != 0 {
+			w.WriteString(c.stackSep)
+		}
+		Frame(pc).writeTo(w, c.funcSep, c.trim, c.deterministic)
+	}
+	return true
+}
+
+// layer is implemented by the error types of this package, so renderers can
+// reach a layer's own message and stack without going through Error().
+// The Error() of a layer is its message joined by ": " to the Error() of
+// its cause, which lets writeError compose whole chains in one pass.
+type layer interface {
+	layerMessage() string
+	layerStack() *stack
+	// layerFormat returns the options of the api that created the layer,
+	// or nil if it has none of its own.
+	layerFormat() *Config
+}
@@ -119,3 +119,147 @@ func TestLinesNoStack(t *testing.T) {
 		assert.Equal(t, tt.lines, got)
 	}
 }
+
+type verboseError struct{}
+
+func (verboseError) Error() string { return "verbose" }
+
+func (verboseError) ErrorLineV(level Verbosity) string {
+	switch level {
+	case VerbosityTerse:
+		return "v"
+	case VerbosityDebug:
+		return "verbose (debug)"
+	}
+	return "verbose"
+}
+
+func TestLinesV(t *testing.T) {
+	err := WithMessage(verboseError{}, "bar")
+	assert.Equal(t, []string{"bar", "v"}, LinesV(err, VerbosityTerse))
+	assert.Equal(t, []string{"bar", "verbose"}, LinesV(err, VerbosityNormal))
+	assert.Equal(t, []string{"bar", "verbose (debug)"}, LinesV(err, VerbosityDebug))
+	assert.Equal(t, []string{"bar", "verbose (debug)"}, Lines(err, true))
+	assert.Equal(t, "verbose (debug)\nbar", fmt.Sprintf("%+v", err))
+}
+
+func TestLinesWith(t *testing.T) {
+	err := WithMessage(WithStack(Wrap(New("foo"), "bar")), "baz")
+
+	matchLines(t, []string{
+		"baz",
+		"bar",
+		"foo",
+	}, LinesWith(err))
+	assert.Equal(t, []string{"baz", "", "bar", "foo"}, LinesWith(err, LineEmptyLayers(EmptyKeep)))
+	matchLines(t, []string{
+		"baz",
+		"^github.com/pkg/errors.TestLinesWith \\| github.com/pkg/errors/lines_test.go:\\d+$",
+		"^bar \\| github.com/pkg/errors.TestLinesWith \\| github.com/pkg/errors/lines_test.go:\\d+$",
+		"^foo \\| github.com/pkg/errors.TestLinesWith \\| github.com/pkg/errors/lines_test.go:\\d+$",
+	}, LinesWith(err,
+		LineVerbosity(VerbosityDebug),
+		LineSeparators(" | ", " ; ", " | "),
+		LineTrimPrefix("/nonexistent/", trimPrefix(t)),
+	))
+}
+
+// trimPrefix returns the part of this file's path that precedes the
+// import path of the package.
+func trimPrefix(t *testing.T) string {
+	m := regexp.MustCompile(`^(.*/)github\.com/pkg/errors/`).FindStringSubmatch(initpc.file())
+	if m == nil {
+		t.Fatalf("unexpected file name %q", initpc.file())
+	}
+	return m[1]
+}
+
+func TestLinesWithFrames(t *testing.T) {
+	err := NewErrorsApi(ApiConfig{CallerSkip: 1}).New("foo")
+	got := LinesWith(err, LineVerbosity(VerbosityDebug), LineFrames(1))
+	matchLines(t, []string{"^foo\ngithub.com/pkg/errors.TestLinesWithFrames\t.+/github.com/pkg/errors/lines_test.go:\\d+$"}, got)
+}
+
+func TestWriteLines(t *testing.T) {
+	err := Wrap(WithStack(NewCodeError(1, "foo")), "bar")
+	tests := []struct {
+		stack bool
+		want  string
+	}{
+		{false, "bar\ncode error: 1, foo\n"},
+		{true, "^bar\ngithub.com/pkg/errors.TestWriteLines\t.+/github.com/pkg/errors/lines_test.go:\\d+\n" +
+			"github.com/pkg/errors.TestWriteLines\t.+/github.com/pkg/errors/lines_test.go:\\d+\n" +
+			"code error: 1, foo\n$"},
+	}
+
+	for _, tt := range tests {
+		var buf recorder
+		assert.NoError(t, WriteLines(&buf, err, tt.stack))
+		assert.Regexp(t, tt.want, string(buf))
+	}
+	assert.Error(t, WriteLines(failingWriter{}, err, false))
+}
+
+type recorder []byte
+
+func (r *recorder) Write(p []byte) (int, error) {
+	*r = append(*r, p...)
+	return len(p), nil
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, fmt.Errorf("short write") }
+
+func TestLinesWithDedup(t *testing.T) {
+	err := New("foo")
+	for i := 0; i < 3; i++ {
+		err = Wrap(err, "retry")
+	}
+	err = WithMessage(Wrap(err, "retry"), "done")
+
+	assert.Equal(t, []string{"done", "retry (repeated 4 times)", "foo"}, LinesWith(err, LineDedup(true)))
+	assert.Equal(t, []string{"done", "retry", "retry", "retry", "retry", "foo"}, LinesWith(err))
+	matchLines(t, []string{
+		"^done$",
+		"^retry \\(repeated 4 times\\)\ngithub.com/pkg/errors.TestLinesWithDedup\t.+/github.com/pkg/errors/lines_test.go:\\d+\n" +
+			"github.com/pkg/errors.TestLinesWithDedup\t.+/github.com/pkg/errors/lines_test.go:\\d+$",
+		"^foo\ngithub.com/pkg/errors.TestLinesWithDedup\t.+/github.com/pkg/errors/lines_test.go:\\d+$",
+	}, LinesWith(err, LineDedup(true), LineVerbosity(VerbosityDebug)))
+}
+
+func TestWrapIfStack(t *testing.T) {
+	matchLines(t, []string{
+		"^read\ngithub.com/pkg/errors.TestWrapIfStack\t.+/github.com/pkg/errors/lines_test.go:\\d+$",
+		"^read 1\ngithub.com/pkg/errors.TestWrapIfStack\t.+/github.com/pkg/errors/lines_test.go:\\d+$",
+		"EOF",
+	}, Lines(WrapIf(true, WrapfIf(true, fmt.Errorf("EOF"), "read %d", 1), "read"), true))
+}
+
+func TestMessages(t *testing.T) {
+	assert.Equal(t, []string{}, Messages(nil))
+	err := WithMessage(WithCode(Wrap(WithStack(fmt.Errorf("EOF")), "read"), CodeInternal), "load")
+	assert.Equal(t, []string{"load", "read", "EOF"}, Messages(err))
+	assert.Equal(t, Lines(err, false), Messages(err))
+
+	joined := Wrap(Join(New("bar"), New("foo")), "both")
+	assert.Equal(t, Lines(joined, false), Messages(joined))
+}
+
+func TestLinesWithEmptyLayers(t *testing.T) {
+	err := WithDetails(WithMessage(WithCode(WithStack(New("foo")), CodeNotFound), "bar"), "user", "bob", "attempt", 2)
+
+	assert.Equal(t, []string{"bar", "foo"}, LinesWith(err))
+	assert.Equal(t, []string{"", "bar", "", "", "foo"}, LinesWith(err, LineEmptyLayers(EmptyKeep)))
+	assert.Equal(t, []string{"[user=bob attempt=2]", "bar", "[code=NotFound]", "[stack]", "foo"},
+		LinesWith(err, LineEmptyLayers(EmptyPlaceholder)))
+	assert.Equal(t, []string{"[user=bob attempt=2] bar", "[code=NotFound stack] foo"},
+		LinesWith(err, LineEmptyLayers(EmptyMerge)))
+
+	var buf recorder
+	assert.NoError(t, WriteLinesWith(&buf, err, LineEmptyLayers(EmptyMerge)))
+	assert.Equal(t, "[user=bob attempt=2] bar\n[code=NotFound stack] foo\n", string(buf))
+
+	trailing := WithHint(WithStack(fmt.Errorf("")), "try again")
+	assert.Equal(t, []string{`[hint="try again" stack *errors.errorString]`}, LinesWith(trailing, LineEmptyLayers(EmptyMerge)))
+}
@@ -1,20 +1,62 @@
 package errors
 
+import (
+	"sync"
+	"sync/atomic"
+)
+
 type Config struct {
 	FuncSep  string
 	StackSep string
 	MsgSep   string
+	// TrimPrefixes are stripped from the file names of formatted frames,
+	// the first matching one only, so that traces do not depend on where
+	// the program was built.
+	TrimPrefixes []string
+	// Deterministic formats frames as their function name and the base
+	// name of their file only, without the directory or the line number,
+	// so that snapshots of formatted errors in tests do not change every
+	// time unrelated lines are added to a file.
+	Deterministic bool
 }
 
 type Option func(*Config)
 
 var (
-	globalOptions = Config{
+	// globalOptions holds the *Config set by SetOptions. It is replaced as a
+	// whole on every change, so errors can be formatted while other
+	// goroutines call SetOptions.
+	globalOptions atomic.Value
+	optionsMu     sync.Mutex
+)
+
+func init() {
+	globalOptions.Store(defaultConfig())
+}
+
+// defaultConfig returns the options in effect before any call to SetOptions.
+func defaultConfig() *Config {
+	return &Config{
 		FuncSep:  "\t",
 		StackSep: "\n",
 		MsgSep:   "\n",
 	}
-)
+}
+
+// options returns the current package level options. The returned Config
+// must not be modified.
+func options() *Config {
+	return globalOptions.Load().(*Config)
+}
+
+// formatOptions returns c, the options of the api that created an error,
+// or the package level options if that api has none of its own.
+func formatOptions(c *Config) *Config {
+	if c == nil {
+		return options()
+	}
+	return c
+}
 
 func WithFuncSep(sep string) Option {
 	return func(c *Config) {
@@ -34,8 +76,46 @@ func WithMsgSep(sep string) Option {
 	}
 }
 
+// WithTrimPrefix adds prefixes to strip from the file names of formatted
+// frames, such as the module root or a build sandbox directory.
+func WithTrimPrefix(prefixes ...string) Option {
+	return func(c *Config) {
+		c.TrimPrefixes = append(c.TrimPrefixes[:len(c.TrimPrefixes):len(c.TrimPrefixes)], prefixes...)
+	}
+}
+
+// WithDeterministicFrames sets Config.Deterministic. Tests usually enable it
+// for a single api, with WithFormatOptions, rather than for the package:
+//
+//	api := errors.NewErrorsApi(errors.WithFormatOptions(errors.WithDeterministicFrames(true)))
+func WithDeterministicFrames(deterministic bool) Option {
+	return func(c *Config) {
+		c.Deterministic = deterministic
+	}
+}
+
 func SetOptions(options ...Option) {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	c := *globalOptions.Load().(*Config)
 	for _, option := range options {
-		option(&globalOptions)
+		option(&c)
+	}
+	globalOptions.Store(&c)
+}
+
+// SnapshotOptions records the current package level options and returns a
+// function that restores them. It is meant to be deferred by tests that
+// call SetOptions, so that their changes do not leak into other tests even
+// if they panic:
+//
+//	defer errors.SnapshotOptions()()
+//	errors.SetOptions(errors.WithMsgSep(": "))
+func SnapshotOptions() (restore func()) {
+	c := options()
+	return func() {
+		optionsMu.Lock()
+		defer optionsMu.Unlock()
+		globalOptions.Store(c)
 	}
 }
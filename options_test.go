@@ -0,0 +1,92 @@
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetOptionsConcurrently(t *testing.T) {
+	defer SetOptions(WithMsgSep("\n"), WithStackSep("\n"), WithFuncSep("\t"))
+
+	err := Wrap(New("foo"), "bar")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				SetOptions(WithMsgSep(fmt.Sprint(i)), WithStackSep(fmt.Sprint(j)))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = fmt.Sprintf("%+v", err)
+				_ = Lines(err, true)
+			}
+		}()
+	}
+	wg.Wait()
+
+	SetOptions(WithMsgSep(" @ "), WithFuncSep(" "))
+	assert.Regexp(t, "^bar @ github.com/pkg/errors.TestSetOptionsConcurrently ", Lines(err, true)[0])
+}
+
+func TestSnapshotOptions(t *testing.T) {
+	want := *options()
+	func() {
+		defer func() { recover() }()
+		defer SnapshotOptions()()
+		SetOptions(WithMsgSep(": "), WithFuncSep(" "))
+		assert.Equal(t, ": ", options().MsgSep)
+		panic("test")
+	}()
+	assert.Equal(t, want, *options())
+}
+
+func TestSnapshotGlobalApi(t *testing.T) {
+	before := globalApi()
+	func() {
+		defer SnapshotGlobalApi()()
+		SetGlobalApi(NewErrorsApi(WithCallerSkip(2), WithMaxDepth(4)))
+		assert.NotSame(t, before, globalApi())
+	}()
+	assert.Same(t, before, globalApi())
+}
+
+func TestTrimPrefix(t *testing.T) {
+	defer SnapshotOptions()()
+	_, file, _, _ := runtime.Caller(0)
+	SetOptions(WithTrimPrefix(file[:strings.LastIndex(file, "/")+1]))
+
+	err := New("trimmed")
+	assert.Regexp(t, "^trimmed\ngithub.com/pkg/errors.TestTrimPrefix\toptions_test.go:\\d+$", fmt.Sprintf("%+v", err))
+	matchLines(t, []string{"^trimmed\ngithub.com/pkg/errors.TestTrimPrefix\toptions_test.go:\\d+$"}, Lines(err, true))
+	text, _ := err.(*fundamental).StackTrace()[0].MarshalText()
+	assert.Regexp(t, "^github.com/pkg/errors.TestTrimPrefix options_test.go:\\d+$", string(text))
+	assert.Regexp(t, "^\ngithub.com/pkg/errors.TestTrimPrefix\toptions_test.go:\\d+$", fmt.Sprintf("%+v", err.(*fundamental).StackTrace()))
+}
+
+func TestDeterministicFrames(t *testing.T) {
+	api := NewErrorsApi(WithMaxDepth(2), WithFormatOptions(WithDeterministicFrames(true)))
+	err := api.Wrap(api.New("foo"), "bar")
+	want := "foo\ngithub.com/pkg/errors.TestDeterministicFrames\toptions_test.go\ntesting.tRunner\ttesting.go\n" +
+		"bar\ngithub.com/pkg/errors.TestDeterministicFrames\toptions_test.go\ntesting.tRunner\ttesting.go"
+	assert.Equal(t, want, fmt.Sprintf("%+v", err))
+	assert.Equal(t, "bar\ngithub.com/pkg/errors.TestDeterministicFrames\toptions_test.go\ntesting.tRunner\ttesting.go",
+		err.(Liner).ErrorLineV(VerbosityDebug))
+
+	defer SnapshotOptions()()
+	SetOptions(WithDeterministicFrames(true))
+	frame := err.(*withStack).StackTrace()[0]
+	assert.Equal(t, "github.com/pkg/errors.TestDeterministicFrames\toptions_test.go", frame.String())
+	text, _ := frame.MarshalText()
+	assert.Equal(t, "github.com/pkg/errors.TestDeterministicFrames options_test.go", string(text))
+	data, _ := frame.MarshalJSON()
+	assert.Equal(t, `{"function":"github.com/pkg/errors.TestDeterministicFrames","file":"options_test.go"}`, string(data))
+}
@@ -0,0 +1,129 @@
+package errors
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// PayloadKeyPrefix prefixes the detail keys WithPayload stores payloads
+// under, which end with the name of the payload.
+const PayloadKeyPrefix = "errors.payload."
+
+// DefaultPayloadLimit is the number of bytes of a payload WithPayload keeps
+// when ApiConfig.PayloadLimit is zero.
+const DefaultPayloadLimit = 4096
+
+// Payload is the copy of a request or response body attached to an error
+// by WithPayload.
+type Payload struct {
+	// Body is the beginning of the body, redacted and cut to the limit of
+	// the api that attached it.
+	Body []byte
+	// Size is the size of the whole body, before it was redacted and cut.
+	Size int
+	// Truncated reports whether Body was cut.
+	Truncated bool
+}
+
+// String returns the body, quoted, followed by its size if it was cut.
+func (p Payload) String() string {
+	s := strconv.Quote(string(p.Body))
+	if p.Truncated {
+		s += " (" + strconv.Itoa(p.Size) + " bytes, truncated)"
+	}
+	return s
+}
+
+// MarshalJSON formats p as an object with the body as a string, its size
+// and whether it was truncated.
+func (p Payload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Body      string `json:"body"`
+		Size      int    `json:"size"`
+		Truncated bool   `json:"truncated,omitempty"`
+	}{string(p.Body), p.Size, p.Truncated})
+}
+
+// WithPayload annotates err with a copy of body, the payload of the request
+// or response that failed with it, as a Payload detail under the key
+// PayloadKeyPrefix+name, so that the payload shows with the details of the
+// error. The body is passed to the PayloadRedactor of the api, if it has
+// one, then cut to its PayloadLimit, so that neither secrets nor huge
+// bodies end up in the error.
+// If err is nil, WithPayload returns nil.
+func WithPayload(err error, name string, body []byte) error {
+	return globalErrorsApi().WithPayload(err, name, body)
+}
+
+// Payloads returns the payloads attached to err's chain by WithPayload, by
+// name. When a name is used more than once, the outermost payload wins.
+func Payloads(err error) map[string]Payload {
+	var payloads map[string]Payload
+	for key, v := range Details(err) {
+		p, ok := v.(Payload)
+		if !ok || len(key) <= len(PayloadKeyPrefix) || key[:len(PayloadKeyPrefix)] != PayloadKeyPrefix {
+			continue
+		}
+		if payloads == nil {
+			payloads = make(map[string]Payload)
+		}
+		payloads[key[len(PayloadKeyPrefix):]] = p
+	}
+	return payloads
+}
+
+// formatPayloads writes the payloads among details for %+v, each on a line
+// of its own that starts with "payload " and the name of the payload.
+func formatPayloads(w io.Writer, details []detail) {
+	for _, d := range details {
+		p, ok := d.value.(Payload)
+		if !ok || !strings.HasPrefix(d.key, PayloadKeyPrefix) {
+			continue
+		}
+		io.WriteString(w, "\npayload "+d.key[len(PayloadKeyPrefix):]+": "+p.String())
+	}
+}
+
+// WithPayloadLimit sets ApiConfig.PayloadLimit.
+func WithPayloadLimit(limit int) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.PayloadLimit = limit
+	})
+}
+
+// WithPayloadRedactor sets ApiConfig.PayloadRedactor.
+func WithPayloadRedactor(redact func(name string, body []byte) []byte) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.PayloadRedactor = redact
+	})
+}
+
+func (e *errorsApi) WithPayload(err error, name string, body []byte) error {
+	if err == nil {
+		return nil
+	}
+	size := len(body)
+	if e.cfg.PayloadRedactor != nil {
+		body = e.cfg.PayloadRedactor(name, body)
+	}
+	limit := e.cfg.PayloadLimit
+	if limit == 0 {
+		limit = DefaultPayloadLimit
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	truncated := len(body) > limit
+	if truncated {
+		n := limit
+		for n > 0 && !utf8.RuneStart(body[n]) {
+			n--
+		}
+		body = body[:n]
+	}
+	p := Payload{Body: append([]byte(nil), body...), Size: size, Truncated: truncated}
+	return &withDetails{annotation{err}, []detail{{PayloadKeyPrefix + name, p}}}
+}
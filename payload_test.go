@@ -0,0 +1,52 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPayload(t *testing.T) {
+	assert.Nil(t, WithPayload(nil, "response", []byte("{}")))
+
+	body := []byte(`{"error":"quota exceeded"}`)
+	err := WithPayload(Wrap(io.EOF, "call"), "response", body)
+	body[0] = '['
+	p := Payloads(err)["response"]
+	assert.Equal(t, Payload{Body: []byte(`{"error":"quota exceeded"}`), Size: 26}, p)
+	assert.Equal(t, p, Details(err)[PayloadKeyPrefix+"response"])
+	assert.Equal(t, `"{\"error\":\"quota exceeded\"}"`, p.String())
+	assert.Equal(t, []string{`[errors.payload.response="{\"error\":\"quota exceeded\"}"]`}, LinesWith(err, LineEmptyLayers(EmptyPlaceholder))[:1])
+	assert.Nil(t, Payloads(io.EOF))
+
+	big := bytes.Repeat([]byte("é"), 3000)
+	p = Payloads(WithPayload(io.EOF, "request", big))["request"]
+	assert.Len(t, p.Body, DefaultPayloadLimit)
+	assert.True(t, p.Truncated)
+	assert.Equal(t, 6000, p.Size)
+	assert.True(t, strings.HasSuffix(p.String(), `" (6000 bytes, truncated)`))
+
+	api := NewErrorsApi(WithPayloadLimit(5), WithPayloadRedactor(func(name string, body []byte) []byte {
+		return bytes.ReplaceAll(body, []byte("hunter2"), []byte("***"))
+	}))
+	p = Payloads(api.WithPayload(io.EOF, "request", []byte("pw=hunter2")))["request"]
+	assert.Equal(t, Payload{Body: []byte("pw=**"), Size: 10, Truncated: true}, p)
+	b, jerr := json.Marshal(p)
+	assert.NoError(t, jerr)
+	assert.JSONEq(t, `{"body":"pw=**","size":10,"truncated":true}`, string(b))
+
+	p = Payloads(NewErrorsApi(WithPayloadLimit(-1)).WithPayload(io.EOF, "request", body))["request"]
+	assert.Equal(t, Payload{Size: 26, Truncated: true}, p)
+}
+
+func TestFormatPayload(t *testing.T) {
+	err := WithPayload(New("request failed"), "response", []byte(`{"error":"denied"}`))
+	assert.True(t, strings.HasSuffix(fmt.Sprintf("%+v", err), "\npayload response: \"{\\\"error\\\":\\\"denied\\\"}\""))
+	assert.Equal(t, "request failed", fmt.Sprintf("%v", err))
+	assert.NotContains(t, fmt.Sprintf("%+v", WithDetails(New("request failed"), "user", "bob")), "\npayload ")
+}
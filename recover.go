@@ -0,0 +1,85 @@
+package errors
+
+import "fmt"
+
+// RecoverTo recovers from a panic and stores it in *errp as an error with a
+// stack trace starting where the panic was raised. If *errp already holds
+// an error, the two are joined. RecoverTo must be deferred directly, so that
+// it can recover:
+//
+//	func Parse(s string) (v *Value, err error) {
+//	        defer errors.RecoverTo(&err)
+//	        ...
+//	}
+//
+// The error is a *PanicError. Panic values that are errors are wrapped with
+// the message "panic"; other values are formatted into the message, as in
+// "panic: boom".
+func RecoverTo(errp *error) {
+	if r := recover(); r != nil {
+		globalErrorsApi().recovered(errp, r)
+	}
+}
+
+// RecoverTo is like the package level RecoverTo. It must be deferred
+// directly as well.
+func (e *errorsApi) RecoverTo(errp *error) {
+	if r := recover(); r != nil {
+		e.recovered(errp, r)
+	}
+}
+
+// WrapPanic returns r, a value returned by recover, as an error with a stack
+// trace starting where the panic was raised, a *PanicError, as RecoverTo
+// stores it.
+// WrapPanic returns nil if r is nil. It must be called by the deferred
+// function that recovered r, while the panic is still unwinding.
+func WrapPanic(r interface{}) error {
+	if r == nil {
+		return nil
+	}
+	return globalErrorsApi().panicError(r)
+}
+
+// recovered stores r, the value of a panic recovered by the caller of
+// recovered, in *errp.
+func (e *errorsApi) recovered(errp *error, r interface{}) {
+	err := e.panicError(r)
+	if *errp != nil {
+		err = e.Join(*errp, err)
+	}
+	*errp = err
+}
+
+// PanicError is the error RecoverTo and WrapPanic convert panics to. It can
+// be found with As, to tell panics from ordinary errors and to get the value
+// the panic was raised with. It formats as the error it annotates, which
+// carries the message and the stack of the panic.
+type PanicError struct {
+	annotation
+	value interface{}
+}
+
+// Value returns the value the panic was raised with.
+func (p *PanicError) Value() interface{} { return p.value }
+
+func (p *PanicError) rewrap(cause error) error {
+	c := *p
+	c.cause = cause
+	return &c
+}
+
+// panicError converts r to a *PanicError carrying the stack of the panic.
+func (e *errorsApi) panicError(r interface{}) error {
+	var st *stack
+	if !e.cfg.DisableStack {
+		st = panicCallers(1, e.cfg.Depth)
+	}
+	var err error
+	if cause, ok := r.(error); ok {
+		err = e.withStack(cause, "panic", nil, st)
+	} else {
+		err = e.created(e.fundamental(fmt.Sprintf("panic: %v", r), st, nil), st)
+	}
+	return &PanicError{annotation{err}, r}
+}
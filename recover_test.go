@@ -0,0 +1,95 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recoverValue(v interface{}) (err error) {
+	defer RecoverTo(&err)
+	panic(v)
+}
+
+func recoverIndex(i int) (err error) {
+	defer RecoverTo(&err)
+	var s []int
+	_ = s[i]
+	return nil
+}
+
+func recoverJoined() (err error) {
+	defer RecoverTo(&err)
+	defer func() { err = io.ErrUnexpectedEOF }()
+	panic("boom")
+}
+
+func TestRecoverTo(t *testing.T) {
+	err := recoverValue("boom")
+	assert.EqualError(t, err, "panic: boom")
+	assert.Regexp(t, "^panic: boom\ngithub.com/pkg/errors.recoverValue\t.+/github.com/pkg/errors/recover_test.go:13$", fmt.Sprintf("%+v", err))
+
+	err = recoverValue(io.EOF)
+	assert.EqualError(t, err, "panic: EOF")
+	assert.True(t, Is(err, io.EOF))
+	assert.Regexp(t, "^EOF\npanic\ngithub.com/pkg/errors.recoverValue\t.+/github.com/pkg/errors/recover_test.go:13$", fmt.Sprintf("%+v", err))
+
+	err = recoverIndex(3)
+	assert.Regexp(t, "^panic: runtime error: index out of range", err.Error())
+	assert.Regexp(t, "\ngithub.com/pkg/errors.recoverIndex\t.+/github.com/pkg/errors/recover_test.go:19$", fmt.Sprintf("%+v", err))
+
+	err = recoverJoined()
+	assert.EqualError(t, err, "unexpected EOF\npanic: boom")
+
+	err = func() (err error) {
+		defer RecoverTo(&err)
+		return io.EOF
+	}()
+	assert.Same(t, io.EOF, err)
+}
+
+func TestApiRecoverTo(t *testing.T) {
+	api := NewErrorsApi(WithDisableStack(true))
+	err := func() (err error) {
+		defer api.RecoverTo(&err)
+		panic("boom")
+	}()
+	assert.EqualError(t, err, "panic: boom")
+	assert.False(t, hasStack(err))
+}
+
+func TestWrapPanic(t *testing.T) {
+	assert.Nil(t, WrapPanic(nil))
+
+	var err error
+	func() {
+		defer func() {
+			err = WrapPanic(recover())
+		}()
+		recoverIndex(-1)
+		panic("boom")
+	}()
+	assert.EqualError(t, err, "panic: boom")
+	assert.Regexp(t, "^panic: boom\ngithub.com/pkg/errors.TestWrapPanic.func1\t.+/github.com/pkg/errors/recover_test.go:72$", fmt.Sprintf("%+v", err))
+}
+
+type sentinel struct{ reason string }
+
+func TestPanicError(t *testing.T) {
+	err := recoverValue(sentinel{"abort"})
+	var perr *PanicError
+	assert.True(t, As(err, &perr))
+	assert.Equal(t, sentinel{"abort"}, perr.Value())
+	assert.EqualError(t, err, "panic: {abort}")
+
+	err = Wrap(recoverValue(io.EOF), "parse")
+	assert.True(t, As(err, &perr))
+	assert.Same(t, io.EOF, perr.Value())
+	assert.True(t, Is(err, io.EOF))
+	assert.Equal(t, []string{"parse", "panic", "EOF"}, Lines(err, false))
+
+	assert.False(t, As(Wrap(io.EOF, "read"), &perr))
+	assert.EqualError(t, ReplaceCause(recoverValue(io.EOF), io.ErrUnexpectedEOF), "panic: unexpected EOF")
+}
@@ -0,0 +1,43 @@
+package errors
+
+// Resource identifies an entity an error concerns.
+type Resource struct {
+	Kind string
+	ID   string
+}
+
+// WithResource annotates err with the resource it concerns, such as the
+// user or file an operation failed on, for API responses and audit logs.
+// If err is nil, WithResource returns nil.
+func WithResource(err error, kind, id string) error {
+	return globalErrorsApi().WithResource(err, kind, id)
+}
+
+// Resources returns the resources attached to err's chain, outermost first.
+func Resources(err error) []Resource {
+	var resources []Resource
+	for ; err != nil; err = unwrapOnce(err) {
+		if r, ok := err.(*withResource); ok {
+			resources = append(resources, r.resource)
+		}
+	}
+	return resources
+}
+
+type withResource struct {
+	annotation
+	resource Resource
+}
+
+func (w *withResource) rewrap(cause error) error {
+	c := *w
+	c.cause = cause
+	return &c
+}
+
+func (e *errorsApi) WithResource(err error, kind, id string) error {
+	if err == nil {
+		return nil
+	}
+	return &withResource{annotation{err}, Resource{kind, id}}
+}
@@ -0,0 +1,20 @@
+package errors
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResources(t *testing.T) {
+	assert.Nil(t, WithResource(nil, "user", "1"))
+	assert.Nil(t, Resources(io.EOF))
+
+	err := WithResource(io.EOF, "file", "/etc/hosts")
+	err = WithResource(Wrap(err, "read"), "user", "123")
+	assert.EqualError(t, err, "read: EOF")
+	assert.Equal(t, []Resource{{"user", "123"}, {"file", "/etc/hosts"}}, Resources(err))
+	assert.Equal(t, Resources(err), Resources(WithoutStack(err)))
+	assert.False(t, Equal(err, WithResource(Wrap(WithResource(io.EOF, "file", "/etc/hosts"), "read"), "user", "124")))
+}
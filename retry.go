@@ -0,0 +1,206 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WithRetryable marks err as worth retrying or not, for IsRetryable and
+// Retry. If err is nil, WithRetryable returns nil.
+func WithRetryable(err error, retryable bool) error {
+	return globalErrorsApi().WithRetryable(err, retryable)
+}
+
+// IsRetryable reports whether err's chain is marked as retryable by
+// WithRetryable. If it is marked more than once, the outermost mark wins.
+// Unmarked errors are not retryable.
+func IsRetryable(err error) bool {
+	for ; err != nil; err = unwrapOnce(err) {
+		if r, ok := err.(*withRetryable); ok {
+			return r.retryable
+		}
+	}
+	return false
+}
+
+// WithRetryAfter annotates err with the time to wait before retrying the
+// operation that failed with it, as told by a server's Retry-After header,
+// for instance. If err is nil, WithRetryAfter returns nil.
+func WithRetryAfter(err error, d time.Duration) error {
+	return globalErrorsApi().WithRetryAfter(err, d)
+}
+
+// RetryAfter returns the delay attached to the outermost error in err's
+// chain that has one, and whether there was one.
+func RetryAfter(err error) (time.Duration, bool) {
+	for ; err != nil; err = unwrapOnce(err) {
+		if r, ok := err.(*withRetryAfter); ok {
+			return r.delay, true
+		}
+	}
+	return 0, false
+}
+
+type withRetryable struct {
+	annotation
+	retryable bool
+}
+
+func (w *withRetryable) rewrap(cause error) error {
+	c := *w
+	c.cause = cause
+	return &c
+}
+
+type withRetryAfter struct {
+	annotation
+	delay time.Duration
+}
+
+func (w *withRetryAfter) rewrap(cause error) error {
+	c := *w
+	c.cause = cause
+	return &c
+}
+
+// withStopped is the cause of the error Retry returns when ctx is done
+// before the last attempt: it keeps the error of ctx next to the error of
+// that attempt, so that Is and As find both.
+type withStopped struct {
+	annotation
+	stopped error
+}
+
+// Unwrap returns both the error of the last attempt and the error of ctx.
+// Cause still returns the former, which the rest of the chain follows.
+func (w *withStopped) Unwrap() []error { return []error{w.cause, w.stopped} }
+
+func (w *withStopped) rewrap(cause error) error {
+	c := *w
+	c.cause = cause
+	return &c
+}
+
+func (e *errorsApi) WithRetryable(err error, retryable bool) error {
+	if err == nil {
+		return nil
+	}
+	return &withRetryable{annotation{err}, retryable}
+}
+
+func (e *errorsApi) WithRetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &withRetryAfter{annotation{err}, d}
+}
+
+// RetryPolicy configures Retry.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times the operation is tried at most.
+	// Values below 1 mean 1.
+	MaxAttempts int
+	// Backoff returns the delay between attempt n, counting from 1, and
+	// the next one. A nil Backoff retries immediately.
+	Backoff func(n int) time.Duration
+}
+
+// ExponentialBackoff returns a RetryPolicy.Backoff that waits base after
+// the first attempt and doubles the delay after every further one, up to
+// max.
+func ExponentialBackoff(base, max time.Duration) func(n int) time.Duration {
+	return func(n int) time.Duration {
+		d := base
+		for i := 1; i < n && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// RetryAttemptsKey is the detail key Retry stores the history of its
+// attempts under.
+const RetryAttemptsKey = "errors.retry_attempts"
+
+// Attempt records a failed attempt of Retry.
+type Attempt struct {
+	// Err is the message of the error the attempt failed with.
+	Err string
+	// Time is when the attempt failed.
+	Time time.Time
+	// Backoff is the delay waited before the next attempt, zero for the
+	// last one.
+	Backoff time.Duration
+}
+
+// Attempts returns the attempts recorded by Retry in err's chain, in the
+// order they were made.
+func Attempts(err error) []Attempt {
+	attempts, _ := Details(err)[RetryAttemptsKey].([]Attempt)
+	return attempts
+}
+
+// Retry calls fn until it succeeds, returns an error that is not worth
+// retrying, or policy.MaxAttempts attempts were made, and returns nil or
+// the last error. Only the errors IsRetryable reports as retryable, those
+// marked by WithRetryable(err, true), are retried. The delay between
+// attempts is taken from the error by RetryAfter if it has one, and from
+// policy.Backoff otherwise. Retry stops waiting when ctx is done.
+//
+// The last error is wrapped with a message telling the number of attempts
+// and the detail RetryAttemptsKey, which holds the history returned by
+// Attempts, so the earlier failures are not lost.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	return globalErrorsApi().Retry(ctx, policy, fn)
+}
+
+func (e *errorsApi) Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	var attempts []Attempt
+	var err, stopped error
+	for n := 1; ; n++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		attempts = append(attempts, Attempt{Err: err.Error(), Time: e.now()})
+		if n >= policy.MaxAttempts || !IsRetryable(err) {
+			break
+		}
+		delay, ok := RetryAfter(err)
+		if !ok && policy.Backoff != nil {
+			delay = policy.Backoff(n)
+		}
+		attempts[n-1].Backoff = delay
+		if stopped = sleep(ctx, delay); stopped != nil {
+			break
+		}
+	}
+	msg := fmt.Sprintf("after %d attempts", len(attempts))
+	if len(attempts) == 1 {
+		msg = "after 1 attempt"
+	}
+	if stopped != nil {
+		msg += " (" + stopped.Error() + ")"
+		err = &withStopped{annotation{err}, stopped}
+	}
+	return e.WithDetails(e.wrap(1, err, msg), RetryAttemptsKey, attempts)
+}
+
+// sleep waits for d, or until ctx is done, in which case it returns the
+// error of ctx.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
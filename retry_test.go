@@ -0,0 +1,139 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryable(t *testing.T) {
+	assert.Nil(t, WithRetryable(nil, true))
+	assert.Nil(t, WithRetryAfter(nil, time.Second))
+
+	assert.False(t, IsRetryable(io.EOF))
+	err := WithRetryable(io.EOF, true)
+	assert.True(t, IsRetryable(err))
+	assert.True(t, IsRetryable(Wrap(err, "read")))
+	assert.False(t, IsRetryable(WithRetryable(err, false)))
+	assert.Equal(t, "EOF", err.Error())
+
+	_, ok := RetryAfter(err)
+	assert.False(t, ok)
+	d, ok := RetryAfter(Wrap(WithRetryAfter(err, time.Second), "read"))
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, d)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 5*time.Second)
+	assert.Equal(t, time.Second, backoff(1))
+	assert.Equal(t, 2*time.Second, backoff(2))
+	assert.Equal(t, 4*time.Second, backoff(3))
+	assert.Equal(t, 5*time.Second, backoff(4))
+	assert.Equal(t, 5*time.Second, backoff(40))
+}
+
+func TestRetry(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	api := NewErrorsApi(WithClock(func() time.Time { return now }))
+
+	calls := 0
+	err := api.Retry(context.Background(), RetryPolicy{MaxAttempts: 3}, func(ctx context.Context) error {
+		calls++
+		return WithRetryable(fmt.Errorf("attempt %d", calls), true)
+	})
+	assert.Equal(t, 3, calls)
+	assert.EqualError(t, err, "after 3 attempts: attempt 3")
+	assert.Equal(t, []Attempt{
+		{Err: "attempt 1", Time: now},
+		{Err: "attempt 2", Time: now},
+		{Err: "attempt 3", Time: now},
+	}, Attempts(err))
+	assert.Regexp(t, "github.com/pkg/errors.TestRetry\t.+/github.com/pkg/errors/retry_test.go:45$", fmt.Sprintf("%+v", err))
+
+	calls = 0
+	err = Retry(context.Background(), RetryPolicy{MaxAttempts: 3}, func(ctx context.Context) error {
+		calls++
+		if calls == 2 {
+			return nil
+		}
+		return WithRetryable(io.EOF, true)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), RetryPolicy{MaxAttempts: 3}, func(ctx context.Context) error {
+		calls++
+		return WithRetryable(io.EOF, false)
+	})
+	assert.Equal(t, 1, calls)
+	assert.EqualError(t, err, "after 1 attempt: EOF")
+	assert.True(t, Is(err, io.EOF))
+	assert.Len(t, Attempts(err), 1)
+
+	calls = 0
+	err = Retry(context.Background(), RetryPolicy{MaxAttempts: 3}, func(ctx context.Context) error {
+		calls++
+		return io.EOF
+	})
+	assert.Equal(t, 1, calls)
+	assert.EqualError(t, err, "after 1 attempt: EOF")
+}
+
+func TestRetryBackoff(t *testing.T) {
+	var backoffs []int
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		Backoff: func(n int) time.Duration {
+			backoffs = append(backoffs, n)
+			return time.Millisecond
+		},
+	}
+	calls := 0
+	err := Retry(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		if calls == 2 {
+			return WithRetryAfter(WithRetryable(io.EOF, true), 2*time.Millisecond)
+		}
+		return WithRetryable(io.EOF, true)
+	})
+	assert.Equal(t, []int{1}, backoffs)
+	attempts := Attempts(err)
+	assert.Len(t, attempts, 3)
+	assert.Equal(t, time.Millisecond, attempts[0].Backoff)
+	assert.Equal(t, 2*time.Millisecond, attempts[1].Backoff)
+	assert.Equal(t, time.Duration(0), attempts[2].Backoff)
+}
+
+func TestRetryContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Retry(ctx, RetryPolicy{MaxAttempts: 5, Backoff: ExponentialBackoff(time.Hour, time.Hour)}, func(ctx context.Context) error {
+		calls++
+		cancel()
+		return WithRetryable(io.EOF, true)
+	})
+	assert.Equal(t, 1, calls)
+	assert.EqualError(t, err, "after 1 attempt (context canceled): EOF")
+	assert.True(t, Is(err, io.EOF))
+	assert.True(t, Is(err, context.Canceled))
+	assert.True(t, IsRetryable(err))
+	assert.Len(t, Attempts(err), 1)
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	err = Retry(ctx, RetryPolicy{MaxAttempts: 5, Backoff: ExponentialBackoff(time.Hour, time.Hour)}, func(ctx context.Context) error {
+		return WithRetryable(io.ErrUnexpectedEOF, true)
+	})
+	assert.EqualError(t, err, "after 1 attempt (context deadline exceeded): unexpected EOF")
+	assert.True(t, Is(err, io.ErrUnexpectedEOF))
+	assert.True(t, Is(err, context.DeadlineExceeded))
+	assert.False(t, Is(err, context.Canceled))
+}
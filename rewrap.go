@@ -0,0 +1,53 @@
+package errors
+
+// Rewrap returns a copy of err's chain with the message of every layer
+// created by this package replaced by transform(msg), to redact, translate
+// or tag them. Stacks, causes, codes, details and the other annotations are
+// kept, and so are layers without a message, which transform is not called
+// for. The errors of other packages cannot be rebuilt, so Rewrap stops at
+// the first one and keeps it, with everything beneath it, unchanged.
+// If err is nil, Rewrap returns nil.
+func Rewrap(err error, transform func(msg string) string) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *fundamental:
+		c := *e
+		c.msg = rewrapMessage(c.msg, transform)
+		c.tmpl = nil
+		return &c
+	case *withStack:
+		c := e.rewrap(Rewrap(e.cause, transform)).(*withStack)
+		c.msg = rewrapMessage(c.msg, transform)
+		c.tmpl = nil
+		return c
+	case *withMessage:
+		c := e.rewrap(Rewrap(e.cause, transform)).(*withMessage)
+		c.msg = rewrapMessage(c.msg, transform)
+		c.tmpl = nil
+		return c
+	case *flattened:
+		// The message of a flattened chain includes those of its causes.
+		c := *e
+		c.msg = rewrapMessage(c.msg, transform)
+		return &c
+	case *lazyError:
+		return Rewrap(e.get(), transform)
+	case *joinError:
+		errs := make([]error, len(e.errs))
+		for i, err := range e.errs {
+			errs[i] = Rewrap(err, transform)
+		}
+		return &joinError{errs, e.format}
+	case rewrapper:
+		return e.rewrap(Rewrap(unwrapOnce(err), transform))
+	}
+	return err
+}
+
+func rewrapMessage(msg string, transform func(string) string) string {
+	if msg == "" {
+		return msg
+	}
+	return transform(msg)
+}
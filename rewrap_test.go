@@ -0,0 +1,37 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewrap(t *testing.T) {
+	upper := func(msg string) string { return strings.ToUpper(msg) }
+	assert.Nil(t, Rewrap(nil, upper))
+	assert.Same(t, io.EOF, Rewrap(io.EOF, upper))
+
+	calls := 0
+	Rewrap(WithStack(io.EOF), func(msg string) string {
+		calls++
+		return msg
+	})
+	assert.Equal(t, 0, calls)
+
+	err := WithMessage(WithDetails(Wrap(New("secret"), "query"), "table", "users"), "load user")
+	got := Rewrap(err, upper)
+	assert.EqualError(t, got, "LOAD USER: QUERY: SECRET")
+	assert.EqualError(t, err, "load user: query: secret")
+	assert.Equal(t, map[string]interface{}{"table": "users"}, Details(got))
+	assert.Equal(t, nearestStack(err), nearestStack(got))
+	assert.Equal(t, fmt.Sprintf("%+v", WithoutStack(Rewrap(err, upper))), fmt.Sprintf("%+v", Rewrap(WithoutStack(err), upper)))
+
+	mixed := Wrap(fmt.Errorf("std: %w", New("inner")), "outer")
+	assert.EqualError(t, Rewrap(mixed, upper), "OUTER: std: inner")
+
+	joined := Join(New("a"), Wrap(io.EOF, "b"))
+	assert.EqualError(t, Rewrap(joined, upper), "A\nB: EOF")
+}
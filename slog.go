@@ -0,0 +1,63 @@
+//go:build go1.21
+// +build go1.21
+
+package errors
+
+import (
+	"log/slog"
+	"sort"
+)
+
+// SlogReplaceAttr is a slog.HandlerOptions.ReplaceAttr function that
+// rewrites attributes holding errors into groups, for applications that
+// can set handler options but cannot wrap their handler:
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+//	        ReplaceAttr: errors.SlogReplaceAttr,
+//	}))
+//
+// The group of an error has the attributes msg, with its Error() string,
+// code, user_message and hints, when the chain has them, details, a group
+// of the details attached by WithDetails, and stack, the frames of the
+// stack trace of the outermost error in the chain that has one. Other
+// attributes are returned unchanged.
+func SlogReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindAny {
+		return a
+	}
+	err, ok := a.Value.Any().(error)
+	if !ok || err == nil {
+		return a
+	}
+	attrs := []slog.Attr{slog.String("msg", err.Error())}
+	if len(AllCodes(err)) > 0 {
+		attrs = append(attrs, slog.String("code", Code(err).String()))
+	}
+	if msg := UserMessage(err); msg != "" {
+		attrs = append(attrs, slog.String("user_message", msg))
+	}
+	if hints := Hints(err); len(hints) > 0 {
+		attrs = append(attrs, slog.Any("hints", hints))
+	}
+	if details := Details(err); len(details) > 0 {
+		keys := make([]string, 0, len(details))
+		for k := range details {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		group := make([]slog.Attr, len(keys))
+		for i, k := range keys {
+			group[i] = slog.Any(k, details[k])
+		}
+		attrs = append(attrs, slog.Attr{Key: "details", Value: slog.GroupValue(group...)})
+	}
+	if st, opts := nearestStackFormat(err); len(st) > 0 {
+		opts = formatOptions(opts)
+		frames := make([]string, len(st))
+		for i, f := range st {
+			frames[i] = f.stringWith(opts)
+		}
+		attrs = append(attrs, slog.Any("stack", frames))
+	}
+	return slog.Attr{Key: a.Key, Value: slog.GroupValue(attrs...)}
+}
@@ -0,0 +1,44 @@
+//go:build go1.21
+// +build go1.21
+
+package errors
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return SlogReplaceAttr(groups, a)
+		},
+	}))
+
+	logger.Info("plain", "err", io.EOF, "n", 1)
+	assert.Equal(t, `{"level":"INFO","msg":"plain","err":{"msg":"EOF"},"n":1}`+"\n", buf.String())
+
+	buf.Reset()
+	err := WithDetails(WithHint(WithUserMessage(WithCode(New("boom"), CodeInternal), "Try later."), "retry"), "id", 42, "user", "bob")
+	logger.Error("failed", "err", err)
+	assert.Regexp(t, `^{"level":"ERROR","msg":"failed","err":{"msg":"boom","code":"Internal","user_message":"Try later.","hints":\["retry"\],`+
+		`"details":{"id":42,"user":"bob"},"stack":\["github.com/pkg/errors.TestSlogReplaceAttr\\t.+/github.com/pkg/errors/slog_test.go:32"\]}}\n$`, buf.String())
+}
+
+func TestSlogReplaceAttrApiTrimPrefix(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	api := NewErrorsApi(WithFormatOptions(WithTrimPrefix(path.Dir(file) + "/")))
+	attr := SlogReplaceAttr(nil, slog.Any("err", api.New("boom")))
+	stack := attr.Value.Group()[1].Value.Any().([]string)
+	assert.Regexp(t, "^github.com/pkg/errors.TestSlogReplaceAttrApiTrimPrefix\tslog_test.go:\\d+$", stack[0])
+}
@@ -1,12 +1,15 @@
 package errors
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"path"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // Frame represents a program counter inside a stack frame.
@@ -67,7 +70,7 @@ func (f Frame) Format(s fmt.State, verb rune) {
 		switch {
 		case s.Flag('+'):
 			io.WriteString(s, f.name())
-			io.WriteString(s, globalOptions.FuncSep)
+			io.WriteString(s, options().FuncSep)
 			io.WriteString(s, f.file())
 		default:
 			io.WriteString(s, path.Base(f.file()))
@@ -77,12 +80,65 @@ func (f Frame) Format(s fmt.State, verb rune) {
 	case 'n':
 		io.WriteString(s, funcname(f.name()))
 	case 'v':
+		if s.Flag('+') {
+			opts := options()
+			buf := getBuffer()
+			f.writeTo(buf, opts.FuncSep, opts.TrimPrefixes, opts.Deterministic)
+			s.Write(buf.Bytes())
+			putBuffer(buf)
+			return
+		}
 		f.Format(s, 's')
 		io.WriteString(s, ":")
 		f.Format(s, 'd')
 	}
 }
 
+// writeTo writes the %+v form of f to w, using funcSep between the
+// function name and the file, and stripping the first matching prefix in
+// trim from the file name. If deterministic is true, only the base name of
+// the file is written, without the line number.
+func (f Frame) writeTo(w lineWriter, funcSep string, trim []string, deterministic bool) {
+	name, file, line := "unknown", "unknown", 0
+	if fn := runtime.FuncForPC(f.pc()); fn != nil {
+		name = fn.Name()
+		file, line = fn.FileLine(f.pc())
+	}
+	w.WriteString(name)
+	w.WriteString(funcSep)
+	if deterministic {
+		w.WriteString(path.Base(file))
+		return
+	}
+	w.WriteString(trimFile(file, trim))
+	w.WriteByte(':')
+	w.WriteString(strconv.Itoa(line))
+}
+
+// String returns the %+v form of f, using the separator and trimmed
+// prefixes of the package level options.
+func (f Frame) String() string {
+	return f.stringWith(options())
+}
+
+// stringWith is like String, with opts instead of the package level
+// options.
+func (f Frame) stringWith(opts *Config) string {
+	var b strings.Builder
+	f.writeTo(&b, opts.FuncSep, opts.TrimPrefixes, opts.Deterministic)
+	return b.String()
+}
+
+// trimFile strips the first of prefixes that file starts with from file.
+func trimFile(file string, prefixes []string) string {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(file, prefix) {
+			return file[len(prefix):]
+		}
+	}
+	return file
+}
+
 // MarshalText formats a stacktrace Frame as a text string. The output is the
 // same as that of fmt.Sprintf("%+v", f), but without newlines or tabs.
 func (f Frame) MarshalText() ([]byte, error) {
@@ -90,7 +146,45 @@ func (f Frame) MarshalText() ([]byte, error) {
 	if name == "unknown" {
 		return []byte(name), nil
 	}
-	return []byte(fmt.Sprintf("%s %s:%d", name, f.file(), f.line())), nil
+	opts := options()
+	if opts.Deterministic {
+		return []byte(name + " " + path.Base(f.file())), nil
+	}
+	file := trimFile(f.file(), opts.TrimPrefixes)
+	return []byte(fmt.Sprintf("%s %s:%d", name, file, f.line())), nil
+}
+
+// MarshalJSON formats a stacktrace Frame as a JSON object with the
+// function name, file and line of the frame, as in
+//
+//	{"function":"main.main","file":"/home/user/main.go","line":12}
+//
+// File and line are left out of frames that are unknown, and the line
+// is left out if the package level options ask for deterministic frames.
+//
+// The package level options apply, as a Frame does not know the api that
+// captured it; StackJSON formats the stack of an error with the options of
+// its api instead.
+func (f Frame) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.jsonWith(options()))
+}
+
+// jsonWith returns the JSON form of f, with the prefixes of opts trimmed.
+func (f Frame) jsonWith(opts *Config) frameJSON {
+	fj := frameJSON{Function: f.name()}
+	if fj.Function != "unknown" && opts.Deterministic {
+		fj.File = path.Base(f.file())
+	} else if fj.Function != "unknown" {
+		fj.File = trimFile(f.file(), opts.TrimPrefixes)
+		fj.Line = f.line()
+	}
+	return fj
+}
+
+type frameJSON struct {
+	Function string `json:"function"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
 }
 
 // StackTrace is stack of Frames from innermost (newest) to outermost (oldest).
@@ -104,15 +198,24 @@ type StackTrace []Frame
 // Format accepts flags that alter the printing of some verbs, as follows:
 //
 //	%+v   Prints filename, function, and line number for each Frame in the stack.
+//	%+.3v Prints them for the first 3 Frames only.
 func (st StackTrace) Format(s fmt.State, verb rune) {
 	switch verb {
 	case 'v':
 		switch {
 		case s.Flag('+'):
-			for _, f := range st {
-				io.WriteString(s, globalOptions.StackSep)
-				f.Format(s, verb)
+			opts := options()
+			frames := st
+			if n, ok := s.Precision(); ok && n < len(frames) {
+				frames = frames[:n]
 			}
+			buf := getBuffer()
+			for _, f := range frames {
+				buf.WriteString(opts.StackSep)
+				f.writeTo(buf, opts.FuncSep, opts.TrimPrefixes, opts.Deterministic)
+			}
+			s.Write(buf.Bytes())
+			putBuffer(buf)
 		case s.Flag('#'):
 			fmt.Fprintf(s, "%#v", []Frame(st))
 		default:
@@ -123,6 +226,86 @@ func (st StackTrace) Format(s fmt.State, verb rune) {
 	}
 }
 
+// String returns the frames of st in their %+v form, separated by the
+// stack separator of the package level options.
+func (st StackTrace) String() string {
+	return st.stringWith(options())
+}
+
+// stringWith is like String, with opts instead of the package level
+// options.
+func (st StackTrace) stringWith(opts *Config) string {
+	var b strings.Builder
+	for i, f := range st {
+		if i != 0 {
+			b.WriteString(opts.StackSep)
+		}
+		f.writeTo(&b, opts.FuncSep, opts.TrimPrefixes, opts.Deterministic)
+	}
+	return b.String()
+}
+
+// CommonPrefix returns the frames st and other share at their outermost
+// end, that is, the part of the call path from the entry point of the
+// goroutine that both traces went through, innermost first like st. Two
+// errors with the same common prefix as their whole stacks originated from
+// the same call path.
+func (st StackTrace) CommonPrefix(other StackTrace) StackTrace {
+	n := st.commonLen(other)
+	return st[len(st)-n:]
+}
+
+// Diff returns the frames of st and of other that are not part of their
+// CommonPrefix, innermost first: where the two call paths diverged.
+func (st StackTrace) Diff(other StackTrace) (onlySt, onlyOther StackTrace) {
+	n := st.commonLen(other)
+	return st[:len(st)-n], other[:len(other)-n]
+}
+
+// commonLen returns the number of frames st and other share at their
+// outermost end.
+func (st StackTrace) commonLen(other StackTrace) int {
+	n := 0
+	for n < len(st) && n < len(other) && st[len(st)-1-n] == other[len(other)-1-n] {
+		n++
+	}
+	return n
+}
+
+// TopFrames returns at most the first n frames of the stack trace of the
+// outermost error in err's chain that has one. Frames dropped by the frame
+// filter of the api that captured the stack are not included.
+// It returns nil if no error in the chain has a stack trace, or if n is
+// not positive.
+func TopFrames(err error, n int) StackTrace {
+	if n <= 0 {
+		return nil
+	}
+	st := nearestStack(err)
+	if len(st) > n {
+		st = st[:n]
+	}
+	return st
+}
+
+// StackJSON returns the stack trace of the outermost error in err's chain
+// that has one as a JSON array, as json.Marshal formats a StackTrace, but
+// with the frames formatted with the options of the api that created that
+// error, such as its TrimPrefixes, rather than the package level ones.
+// It returns null if no error in the chain has a stack trace.
+func StackJSON(err error) ([]byte, error) {
+	st, opts := nearestStackFormat(err)
+	if st == nil {
+		return json.Marshal(nil)
+	}
+	opts = formatOptions(opts)
+	frames := make([]frameJSON, len(st))
+	for i, f := range st {
+		frames[i] = f.jsonWith(opts)
+	}
+	return json.Marshal(frames)
+}
+
 // formatSlice will format this StackTrace into the given buffer as a slice of
 // Frame, only valid when called with '%s' or '%v'.
 func (st StackTrace) formatSlice(s fmt.State, verb rune) {
@@ -140,22 +323,51 @@ func (st StackTrace) formatSlice(s fmt.State, verb rune) {
 type stack []uintptr
 
 func (s *stack) Format(st fmt.State, verb rune) {
+	if s == nil {
+		return
+	}
 	switch verb {
 	case 'v':
 		switch {
 		case st.Flag('+'):
-			for i, pc := range *s {
-				f := Frame(pc)
-				if i != 0 {
-					fmt.Fprintf(st, globalOptions.StackSep)
-				}
-				fmt.Fprintf(st, "%+v", f)
-			}
+			s.formatWith(st, options())
 		}
 	}
 }
 
+// frames returns the frames of s that are formatted to st: all of them, or
+// as many as the precision of st allows, as in %+.3v.
+func (s *stack) frames(st fmt.State) []uintptr {
+	if s == nil {
+		return nil
+	}
+	if n, ok := st.Precision(); ok && n < len(*s) {
+		return (*s)[:n]
+	}
+	return *s
+}
+
+// formatWith writes the %+v form of s to st using the separators in opts.
+func (s *stack) formatWith(st fmt.State, opts *Config) {
+	if s == nil {
+		return
+	}
+	countFormatted()
+	buf := getBuffer()
+	for i, pc := range s.frames(st) {
+		if i != 0 {
+			buf.WriteString(opts.StackSep)
+		}
+		Frame(pc).writeTo(buf, opts.FuncSep, opts.TrimPrefixes, opts.Deterministic)
+	}
+	st.Write(buf.Bytes())
+	putBuffer(buf)
+}
+
 func (s *stack) StackTrace() StackTrace {
+	if s == nil {
+		return nil
+	}
 	f := make([]Frame, len(*s))
 	for i := 0; i < len(f); i++ {
 		f[i] = Frame((*s)[i])
@@ -163,9 +375,119 @@ func (s *stack) StackTrace() StackTrace {
 	return f
 }
 
-func callers(skip int) *stack {
-	pc, _, _, _ := runtime.Caller(skip + 1)
-	var st stack = []uintptr{pc}
+// bufferPool holds the scratch buffers stacks are rendered into before being
+// written to their destination in one call.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	// Keep unusually large buffers from pinning memory in the pool.
+	if buf.Cap() > 64<<10 {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// DefaultDepth is the number of frames captured per stack trace by apis that
+// do not configure ApiConfig.Depth.
+const DefaultDepth = 1
+
+// pooledDepth is the number of program counters held by the buffers in
+// pcPool.
+const pooledDepth = 32
+
+// pcPool holds scratch buffers for runtime.Callers, so that capturing a
+// stack only allocates the portion that is actually used.
+var pcPool = sync.Pool{
+	New: func() interface{} { return new([pooledDepth]uintptr) },
+}
+
+// callers captures at most depth frames, starting skip frames above its
+// caller. A depth of zero or less selects DefaultDepth; depths beyond the
+// size of the pooled buffers are captured into a buffer of their own.
+func callers(skip, depth int) *stack {
+	if depth <= 0 {
+		depth = DefaultDepth
+	}
+	var pcs []uintptr
+	if depth <= pooledDepth {
+		buf := pcPool.Get().(*[pooledDepth]uintptr)
+		defer pcPool.Put(buf)
+		pcs = buf[:depth]
+	} else {
+		pcs = make([]uintptr, depth)
+	}
+	n := runtime.Callers(skip+2, pcs)
+	st := make(stack, n)
+	for i, pc := range pcs[:n] {
+		// runtime.Callers reports return addresses; step back into the
+		// call instruction so Frame holds the same value as runtime.Caller.
+		st[i] = pc - 1
+	}
+	return &st
+}
+
+// filteredCallers is like callers, but only captures the frames keep
+// returns true for. It walks the stack a pooled buffer at a time until it
+// has found depth frames or reached the bottom of the stack.
+func filteredCallers(skip, depth int, keep func(Frame) bool) *stack {
+	if depth <= 0 {
+		depth = DefaultDepth
+	}
+	buf := pcPool.Get().(*[pooledDepth]uintptr)
+	defer pcPool.Put(buf)
+	st := stack{}
+	for skip += 2; len(st) < depth; skip += pooledDepth {
+		n := runtime.Callers(skip, buf[:])
+		for _, pc := range buf[:n] {
+			if f := Frame(pc - 1); keep(f) {
+				st = append(st, uintptr(f))
+				if len(st) == depth {
+					break
+				}
+			}
+		}
+		if n < pooledDepth {
+			break
+		}
+	}
+	return &st
+}
+
+// panicCallers is like callers, for use while recovering from a panic. The
+// frames between the caller and the runtime raising the panic are left out,
+// so that the stack starts where the panic was raised. At most pooledDepth
+// frames are examined.
+func panicCallers(skip, depth int) *stack {
+	if depth <= 0 {
+		depth = DefaultDepth
+	}
+	buf := pcPool.Get().(*[pooledDepth]uintptr)
+	defer pcPool.Put(buf)
+	n := runtime.Callers(skip+2, buf[:])
+	pcs := buf[:n]
+	for i, pc := range pcs {
+		if Frame(pc-1).name() == "runtime.gopanic" {
+			pcs = pcs[i+1:]
+			break
+		}
+	}
+	for len(pcs) > 0 && strings.HasPrefix(Frame(pcs[0]-1).name(), "runtime.") {
+		pcs = pcs[1:]
+	}
+	if len(pcs) > depth {
+		pcs = pcs[:depth]
+	}
+	st := make(stack, len(pcs))
+	for i, pc := range pcs {
+		st[i] = pc - 1
+	}
 	return &st
 }
 
@@ -176,3 +498,10 @@ func funcname(name string) string {
 	i = strings.Index(name, ".")
 	return name[i+1:]
 }
+
+// pkgFuncname removes the path prefix component of a function's name, but
+// keeps its package name.
+func pkgFuncname(name string) string {
+	i := strings.LastIndex(name, "/")
+	return name[i+1:]
+}
@@ -234,3 +234,89 @@ func caller() Frame {
 	frame, _ := frames.Next()
 	return Frame(frame.PC)
 }
+
+func TestPkgFuncname(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"", ""},
+		{"runtime.main", "runtime.main"},
+		{"github.com/pkg/errors.funcname", "errors.funcname"},
+		{"main.(*R).Write", "main.(*R).Write"},
+		{"github.com/pkg/errors.TestX.func1", "errors.TestX.func1"},
+	}
+
+	for _, tt := range tests {
+		if got := pkgFuncname(tt.name); got != tt.want {
+			t.Errorf("pkgFuncname(%q): want: %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestFrameAndStackTraceString(t *testing.T) {
+	defer SnapshotOptions()()
+	st := New("foo").(*fundamental).StackTrace()[:1]
+	st = append(st, st[0])
+	frame := "github.com/pkg/errors.TestFrameAndStackTraceString\t.+/github.com/pkg/errors/stack_test.go:\\d+"
+	matchLines(t, []string{
+		"^" + frame + "$",
+		"^" + frame + "\n" + frame + "$",
+	}, []string{st[0].String(), st.String()})
+
+	SetOptions(WithFuncSep(" @ "), WithStackSep(" <- "))
+	frame = "github.com/pkg/errors.TestFrameAndStackTraceString @ .+/github.com/pkg/errors/stack_test.go:\\d+"
+	matchLines(t, []string{
+		"^" + frame + " <- " + frame + "$",
+		"^unknown @ unknown:0$",
+	}, []string{st.String(), Frame(0).String()})
+}
+
+func TestTopFrames(t *testing.T) {
+	plain := NewPlain("plain")
+	api := NewErrorsApi(WithMaxDepth(8))
+	err := WithMessage(api.Wrap(plain, "foo"), "bar")
+	want := err.(interface{ StackTrace() StackTrace }).StackTrace()
+
+	tests := []struct {
+		err  error
+		n    int
+		want StackTrace
+	}{
+		{nil, 1, nil},
+		{plain, 1, nil},
+		{err, 0, nil},
+		{err, 1, want[:1]},
+		{err, 2, want[:2]},
+		{err, 100, want},
+	}
+	for _, tt := range tests {
+		if got := TopFrames(tt.err, tt.n); len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("TopFrames(%v, %d): got %v, want %v", tt.err, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestStackTraceCommonPrefixAndDiff(t *testing.T) {
+	api := NewErrorsApi(WithMaxDepth(8))
+	inner := func() StackTrace { return api.New("inner").(*fundamental).StackTrace() }
+	a, b := inner(), api.New("outer").(*fundamental).StackTrace()
+
+	common := a.CommonPrefix(b)
+	if len(common) == 0 || len(common) != len(b)-1 || common[0] != b[1] {
+		t.Fatalf("CommonPrefix: got %v, want %v", common, b[1:])
+	}
+	if got := b.CommonPrefix(a); len(got) != len(common) || got[0] != common[0] {
+		t.Errorf("CommonPrefix is not symmetric: got %v, want %v", got, common)
+	}
+	onlyA, onlyB := a.Diff(b)
+	if len(onlyA) != 2 || onlyA[0] != a[0] || len(onlyB) != 1 || onlyB[0] != b[0] {
+		t.Errorf("Diff: got %v and %v, want %v and %v", onlyA, onlyB, a[:2], b[:1])
+	}
+	if got := a.CommonPrefix(a); len(got) != len(a) {
+		t.Errorf("CommonPrefix with itself: got %v, want %v", got, a)
+	}
+	onlyA, onlyB = a.Diff(nil)
+	if len(onlyA) != len(a) || onlyB != nil {
+		t.Errorf("Diff with nil: got %v and %v, want %v and nil", onlyA, onlyB, a)
+	}
+}
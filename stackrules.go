@@ -0,0 +1,96 @@
+package errors
+
+import (
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StackRule configures how stacks are captured for errors created by the
+// code of some packages.
+type StackRule struct {
+	// Package is the import path of the packages the rule applies to: the
+	// package itself and those below it, as with the "/..." pattern of the
+	// go command, which may be spelled out.
+	Package string
+	// Depth is the maximum number of frames captured per stack trace.
+	// Zero selects DefaultDepth.
+	Depth int
+	// DisableStack makes errors created in the packages carry no stack.
+	DisableStack bool
+}
+
+// WithStackRules adds rules that override ApiConfig.Depth and
+// ApiConfig.DisableStack for errors created in the packages they name, so
+// that stacks can be kept short where errors are frequent and cheap, and
+// complete where they are rare and costly to debug:
+//
+//	api := errors.NewErrorsApi(errors.WithStackRules(
+//	        errors.StackRule{Package: "example.com/app/internal/billing", Depth: 64},
+//	        errors.StackRule{Package: "example.com/app/internal/cache", Depth: 1},
+//	))
+//
+// The package of an error is that of the function that called the api;
+// when several rules match it, the one naming the longest path applies, and
+// the one added last among those naming the same path.
+func WithStackRules(rules ...StackRule) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.StackRules = append(c.StackRules[:len(c.StackRules):len(c.StackRules)], rules...)
+	})
+}
+
+// stackRules looks up the rule of the function that called the api. The
+// rule of each call site is looked up once and cached by program counter.
+type stackRules struct {
+	rules []StackRule // longest path first, then last added first
+	cache sync.Map    // uintptr -> *StackRule, nil if no rule matches
+}
+
+func newStackRules(rules []StackRule) *stackRules {
+	r := &stackRules{rules: make([]StackRule, len(rules))}
+	for i, rule := range rules {
+		rule.Package = strings.TrimSuffix(strings.TrimSuffix(rule.Package, "..."), "/")
+		r.rules[len(rules)-1-i] = rule
+	}
+	sort.SliceStable(r.rules, func(i, j int) bool {
+		return len(r.rules[i].Package) > len(r.rules[j].Package)
+	})
+	return r
+}
+
+// lookup returns the rule of the function skip frames above the caller of
+// lookup, or nil if none applies.
+func (r *stackRules) lookup(skip int) *StackRule {
+	var pc [1]uintptr
+	if runtime.Callers(skip+2, pc[:]) == 0 {
+		return nil
+	}
+	if rule, ok := r.cache.Load(pc[0]); ok {
+		return rule.(*StackRule)
+	}
+	var rule *StackRule
+	if fn := runtime.FuncForPC(pc[0] - 1); fn != nil {
+		pkg := pkgPath(fn.Name())
+		for i := range r.rules {
+			p := r.rules[i].Package
+			if pkg == p || strings.HasPrefix(pkg, p+"/") {
+				rule = &r.rules[i]
+				break
+			}
+		}
+	}
+	r.cache.Store(pc[0], rule)
+	return rule
+}
+
+// pkgPath returns the import path of the package of the function name.
+// The runtime escapes the dots in the last element of the path, as in
+// "gopkg.in/yaml%2ev3.Unmarshal".
+func pkgPath(name string) string {
+	i := strings.LastIndex(name, "/")
+	if j := strings.Index(name[i+1:], "."); j >= 0 {
+		name = name[:i+1+j]
+	}
+	return strings.ReplaceAll(name, "%2e", ".")
+}
@@ -0,0 +1,126 @@
+package errors
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrorStats counts the errors created, wrapped and formatted since stats
+// were enabled with EnableStats.
+type ErrorStats struct {
+	// Created counts the errors created by New, Errorf and the other
+	// constructors of new errors.
+	Created uint64 `json:"created"`
+	// Wrapped counts the wrappers created by Wrap, WithStack, WithMessage
+	// and the other functions that annotate an error with a message or a
+	// stack.
+	Wrapped uint64 `json:"wrapped"`
+	// Formatted counts the stack traces written by the %+v verb.
+	Formatted uint64 `json:"formatted"`
+	// ByCode counts the errors created or wrapped by the name of their
+	// Code.
+	ByCode map[string]uint64 `json:"by_code"`
+	// ByDomain counts the errors created or wrapped by their Domain, for
+	// those that had one at the time.
+	ByDomain map[string]uint64 `json:"by_domain"`
+}
+
+// stats holds the counters behind Stats. Its maps hold *uint64 counters.
+var stats struct {
+	enabled   int32
+	created   uint64
+	wrapped   uint64
+	formatted uint64
+	byCode    sync.Map
+	byDomain  sync.Map
+}
+
+// EnableStats starts or stops counting errors for Stats. Counting is off
+// by default, as finding the code and domain of every error created or
+// wrapped walks its chain.
+func EnableStats(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&stats.enabled, v)
+}
+
+// Stats returns the counts of errors created, wrapped and formatted while
+// stats were enabled.
+func Stats() ErrorStats {
+	return ErrorStats{
+		Created:   atomic.LoadUint64(&stats.created),
+		Wrapped:   atomic.LoadUint64(&stats.wrapped),
+		Formatted: atomic.LoadUint64(&stats.formatted),
+		ByCode:    loadCounters(&stats.byCode),
+		ByDomain:  loadCounters(&stats.byDomain),
+	}
+}
+
+// ResetStats sets the counts returned by Stats back to zero.
+func ResetStats() {
+	atomic.StoreUint64(&stats.created, 0)
+	atomic.StoreUint64(&stats.wrapped, 0)
+	atomic.StoreUint64(&stats.formatted, 0)
+	stats.byCode.Range(func(k, _ interface{}) bool {
+		stats.byCode.Delete(k)
+		return true
+	})
+	stats.byDomain.Range(func(k, _ interface{}) bool {
+		stats.byDomain.Delete(k)
+		return true
+	})
+}
+
+// PublishStats publishes Stats as the expvar variable name, so that it is
+// served with the other variables on /debug/vars. Like expvar.Publish, it
+// panics if name is already in use.
+func PublishStats(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return Stats() }))
+}
+
+func statsEnabled() bool {
+	return atomic.LoadInt32(&stats.enabled) != 0
+}
+
+// countError counts err, created if created is set and wrapped otherwise.
+// Lazy errors are counted without their code, which would build them.
+func countError(err error, created bool) {
+	if created {
+		atomic.AddUint64(&stats.created, 1)
+	} else {
+		atomic.AddUint64(&stats.wrapped, 1)
+	}
+	if _, ok := err.(*lazyError); ok {
+		return
+	}
+	addCounter(&stats.byCode, Code(err).String())
+	if domain, ok := Domain(err); ok {
+		addCounter(&stats.byDomain, domain)
+	}
+}
+
+func countFormatted() {
+	if statsEnabled() {
+		atomic.AddUint64(&stats.formatted, 1)
+	}
+}
+
+func addCounter(m *sync.Map, key string) {
+	c, ok := m.Load(key)
+	if !ok {
+		c, _ = m.LoadOrStore(key, new(uint64))
+	}
+	atomic.AddUint64(c.(*uint64), 1)
+}
+
+func loadCounters(m *sync.Map) map[string]uint64 {
+	counts := make(map[string]uint64)
+	m.Range(func(k, c interface{}) bool {
+		counts[k.(string)] = atomic.LoadUint64(c.(*uint64))
+		return true
+	})
+	return counts
+}
@@ -0,0 +1,44 @@
+package errors
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	EnableStats(true)
+	defer EnableStats(false)
+	defer ResetStats()
+	ResetStats()
+
+	err := NotFoundf("user %d", 1)
+	err = WithDomain(Wrap(err, "load"), "users")
+	err = WithMessage(err, "handle")
+	_ = fmt.Sprintf("%+v", err)
+	_ = NewErrorsApi().New("foo")
+
+	assert.Equal(t, ErrorStats{
+		Created:   2,
+		Wrapped:   2,
+		Formatted: 2,
+		ByCode:    map[string]uint64{"NotFound": 3, "Unknown": 1},
+		ByDomain:  map[string]uint64{"users": 1},
+	}, Stats())
+
+	EnableStats(false)
+	_ = Wrap(io.EOF, "read")
+	assert.Equal(t, uint64(2), Stats().Wrapped)
+
+	ResetStats()
+	assert.Equal(t, ErrorStats{ByCode: map[string]uint64{}, ByDomain: map[string]uint64{}}, Stats())
+
+	PublishStats("errors_test")
+	var got ErrorStats
+	assert.NoError(t, json.Unmarshal([]byte(expvar.Get("errors_test").String()), &got))
+	assert.Equal(t, Stats(), got)
+}
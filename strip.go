@@ -0,0 +1,36 @@
+package errors
+
+// WithoutStack returns a copy of err's chain with every stack trace
+// removed, for sending errors to untrusted clients or storing them
+// compactly. Messages, codes, details and the other annotations of the
+// chain are kept, as are the errors of other packages; those cannot be
+// rebuilt, so the stacks beneath them are kept as well.
+// If err is nil, WithoutStack returns nil.
+func WithoutStack(err error) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *fundamental:
+		if e.stack == nil {
+			return e
+		}
+		return &fundamental{msg: e.msg, format: e.format, render: e.render, tmpl: e.tmpl}
+	case *withStack:
+		cause := WithoutStack(e.cause)
+		if e.msg == "" {
+			return cause
+		}
+		return e.withMessage.rewrap(cause)
+	case *lazyError:
+		return WithoutStack(e.get())
+	case *joinError:
+		errs := make([]error, len(e.errs))
+		for i, err := range e.errs {
+			errs[i] = WithoutStack(err)
+		}
+		return &joinError{errs, e.format}
+	case rewrapper:
+		return e.rewrap(WithoutStack(unwrapOnce(err)))
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithoutStack(t *testing.T) {
+	assert.Nil(t, WithoutStack(nil))
+	assert.Same(t, io.EOF, WithoutStack(io.EOF))
+
+	err := Wrap(WithDetails(New("inner"), "id", 1), "outer")
+	err = WithStack(WithCode(err, CodeNotFound))
+	err = Join(WithHint(err, "retry"), Errorf("other"))
+	stripped := WithoutStack(err)
+
+	assert.Equal(t, err.Error(), stripped.Error())
+	Walk(stripped, func(err error, _ int) bool {
+		if l, ok := err.(layer); ok {
+			assert.Nil(t, l.layerStack(), "%#v", err)
+		}
+		return true
+	})
+	assert.Equal(t, CodeNotFound, Code(stripped.(*joinError).errs[0]))
+	assert.Equal(t, []string{"retry"}, Hints(stripped.(*joinError).errs[0]))
+	assert.Equal(t, map[string]interface{}{"id": 1}, Details(stripped.(*joinError).errs[0]))
+	assert.Equal(t, "inner\nouter\nother", fmt.Sprintf("%+v", stripped))
+
+	// Errors of other packages are kept as they are.
+	std := fmt.Errorf("std: %w", New("inner"))
+	assert.Same(t, std, Cause(WithoutStack(Wrap(std, "outer"))))
+}
+
+func TestWithoutStackCachedError(t *testing.T) {
+	api := NewErrorsApi(WithErrorString(ErrorStringEager))
+	err := api.Wrap(api.New("inner"), "outer")
+	stripped := WithoutStack(err)
+	assert.Equal(t, "outer: inner", stripped.Error())
+	assert.IsType(t, &withMessage{}, stripped)
+}
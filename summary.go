@@ -0,0 +1,59 @@
+package errors
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// maxSummaryMessage is the number of bytes of the message a Summary keeps.
+const maxSummaryMessage = 256
+
+// Summary describes an error in a few fields of bounded size, for metrics
+// events, audit records and other logs where the whole chain would be too
+// large.
+type Summary struct {
+	// RootType is the Go type of the root cause, as in "*fs.PathError".
+	RootType string `json:"root_type"`
+	// Message is the outermost message, cut to 256 bytes.
+	Message string `json:"message"`
+	// Code is the name of the code of the error.
+	Code string `json:"code"`
+	// Fingerprint identifies the errors raised the same way: along the
+	// same stack, with the same root type, code and message template,
+	// whatever their arguments. It is 16 hexadecimal digits.
+	Fingerprint string `json:"fingerprint"`
+	// TopFrame is the first frame of the stack outside the standard
+	// library, as in "example.com/app.run main.go:12", if there is a stack.
+	TopFrame string `json:"top_frame,omitempty"`
+	// Depth is the number of errors in the chain, as returned by Depth.
+	Depth int `json:"depth"`
+}
+
+// Summarize returns the Summary of err. If err is nil, Summarize returns
+// the zero Summary.
+func Summarize(err error) Summary {
+	if err == nil {
+		return Summary{}
+	}
+	s := Summary{
+		RootType: fmt.Sprintf("%T", RootCause(err)),
+		Message:  OutermostMessage(err),
+		Code:     Code(err).String(),
+		Depth:    Depth(err),
+	}
+	if len(s.Message) > maxSummaryMessage {
+		n := maxSummaryMessage
+		for n > 0 && !utf8.RuneStart(s.Message[n]) {
+			n--
+		}
+		s.Message = s.Message[:n]
+	}
+
+	format, _ := MessageTemplate(err)
+	st := nearestStack(err)
+	s.Fingerprint = stackFingerprint(st, s.RootType, s.Code, format)
+	if len(st) > 0 {
+		s.TopFrame = frameSummary(topFrame(st))
+	}
+	return s
+}
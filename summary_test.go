@@ -0,0 +1,40 @@
+package errors
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func summarized(id int) error {
+	return Wrap(NotFoundf("user %d", id), "load user")
+}
+
+func TestSummarize(t *testing.T) {
+	assert.Equal(t, Summary{}, Summarize(nil))
+
+	s := Summarize(summarized(1))
+	assert.Equal(t, "*errors.fundamental", s.RootType)
+	assert.Equal(t, "load user", s.Message)
+	assert.Equal(t, "NotFound", s.Code)
+	assert.Equal(t, 3, s.Depth)
+	assert.Len(t, s.Fingerprint, 16)
+	assert.Regexp(t, `^github.com/pkg/errors.summarized summary_test.go:\d+$`, s.TopFrame)
+
+	assert.Equal(t, s.Fingerprint, Summarize(summarized(2)).Fingerprint)
+	assert.NotEqual(t, s.Fingerprint, Summarize(Wrap(NotFoundf("user %d", 1), "load user")).Fingerprint)
+
+	s = Summarize(WithMessage(io.EOF, strings.Repeat("é", 200)))
+	assert.Equal(t, "*errors.errorString", s.RootType)
+	assert.Equal(t, "Unknown", s.Code)
+	assert.Len(t, s.Message, 256)
+	assert.Empty(t, s.TopFrame)
+	assert.NotEqual(t, s.Fingerprint, Summarize(io.EOF).Fingerprint)
+
+	b, err := json.Marshal(Summarize(io.EOF))
+	assert.NoError(t, err)
+	assert.Regexp(t, `^\{"root_type":"\*errors.errorString","message":"EOF","code":"Unknown","fingerprint":"[0-9a-f]{16}","depth":1\}$`, string(b))
+}
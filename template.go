@@ -0,0 +1,59 @@
+package errors
+
+// msgTemplate holds the format and arguments a message was rendered from.
+type msgTemplate struct {
+	format string
+	args   []interface{}
+}
+
+// MessageTemplate returns the format and the arguments the outermost
+// message of err was rendered from by Errorf, Wrapf, WithMessagef or the
+// other functions that format their message, for fingerprinting errors by
+// their format, translating it, or exporting the arguments as fields.
+// Messages that were not formatted are returned as they are, without
+// arguments, and so is the Error() string of errors from other packages.
+// The arguments are those that were passed, and are not copied: values
+// they point to may have changed since.
+// If err is nil, MessageTemplate returns an empty string.
+func MessageTemplate(err error) (string, []interface{}) {
+	for err != nil {
+		switch e := err.(type) {
+		case *fundamental:
+			return e.tmpl.or(e.msg)
+		case *withStack:
+			if e.msg != "" {
+				return e.tmpl.or(e.msg)
+			}
+		case *withMessage:
+			if e.msg != "" {
+				return e.tmpl.or(e.msg)
+			}
+		case layer:
+			if msg := e.layerMessage(); msg != "" {
+				return msg, nil
+			}
+		default:
+			return err.Error(), nil
+		}
+		err = unwrapOnce(err)
+	}
+	return "", nil
+}
+
+// or returns the format and arguments of t, or msg and no arguments if t
+// is nil.
+func (t *msgTemplate) or(msg string) (string, []interface{}) {
+	if t == nil {
+		return msg, nil
+	}
+	return t.format, t.args
+}
+
+// of returns t, the template a message is being rendered from, or nil if
+// msg is the format itself and recording it would add nothing.
+func (t *msgTemplate) of(msg string) *msgTemplate {
+	if t == nil || len(t.args) == 0 && msg == t.format {
+		return nil
+	}
+	return t
+}
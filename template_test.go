@@ -0,0 +1,46 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageTemplate(t *testing.T) {
+	tests := []struct {
+		err    error
+		format string
+		args   []interface{}
+	}{
+		{nil, "", nil},
+		{io.EOF, "EOF", nil},
+		{New("not found"), "not found", nil},
+		{Errorf("user %d not found", 42), "user %d not found", []interface{}{42}},
+		{Errorf("100%% done"), "100%% done", nil},
+		{NotFoundf("user %q", "bob"), "user %q", []interface{}{"bob"}},
+		{Wrapf(io.EOF, "read %s", "config"), "read %s", []interface{}{"config"}},
+		{WithMessagef(io.EOF, "read %s", "config"), "read %s", []interface{}{"config"}},
+		{Wrap(Errorf("user %d", 1), "load"), "load", nil},
+		{WithStack(WithCode(Errorf("user %d", 1), CodeNotFound)), "user %d", []interface{}{1}},
+		{WithStack(fmt.Errorf("dial: %w", io.EOF)), "dial: EOF", nil},
+		{Rewrap(Errorf("user %d", 1), func(msg string) string { return "[redacted]" }), "[redacted]", nil},
+		{WithoutStack(Errorf("user %d", 1)), "user %d", []interface{}{1}},
+	}
+	for _, tt := range tests {
+		format, args := MessageTemplate(tt.err)
+		assert.Equal(t, tt.format, format, "MessageTemplate(%v)", tt.err)
+		assert.Equal(t, tt.args, args, "MessageTemplate(%v)", tt.err)
+	}
+	assert.EqualError(t, Errorf("user %d not found", 42), "user 42 not found")
+}
+
+func TestMessageTemplateUnchangedCause(t *testing.T) {
+	api := NewErrorsApi(WithDisableStack(true))
+	base := api.New("boom")
+	assert.Same(t, base, api.Wrapf(base, "%s", ""))
+	format, args := MessageTemplate(base)
+	assert.Equal(t, "boom", format)
+	assert.Nil(t, args)
+}
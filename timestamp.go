@@ -0,0 +1,121 @@
+package errors
+
+import (
+	"strings"
+	"time"
+)
+
+// WithClock sets the clock the api reads the time from for WithTime. It
+// defaults to time.Now; tests can install a fixed clock to get
+// deterministic timestamps.
+func WithClock(now func() time.Time) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.Clock = now
+	})
+}
+
+// WithTime annotates err with the time WithTime was called.
+// If err is nil, WithTime returns nil.
+func WithTime(err error) error {
+	return globalErrorsApi().WithTime(err)
+}
+
+// Time returns the time attached to the outermost error in err's chain
+// that has one, and whether there was one.
+func Time(err error) (time.Time, bool) {
+	for ; err != nil; err = unwrapOnce(err) {
+		if t, ok := err.(*withTime); ok {
+			return t.time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+type withTime struct {
+	annotation
+	time time.Time
+}
+
+func (w *withTime) rewrap(cause error) error {
+	c := *w
+	c.cause = cause
+	return &c
+}
+
+func (e *errorsApi) WithTime(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &withTime{annotation{err}, e.now()}
+}
+
+// now returns the current time according to the clock of e.
+func (e *errorsApi) now() time.Time {
+	if e.cfg.Clock != nil {
+		return e.cfg.Clock()
+	}
+	return time.Now()
+}
+
+// Timeline renders err's chain as a timeline, one line per layer with a
+// message, innermost and thus earliest first. The layers the time was
+// attached to with WithTime are prefixed with the time elapsed since the
+// previous one, and the first with its time, as in
+//
+//	2024-05-01T10:00:00Z connection refused
+//	+1.2s retry attempt 2
+//	+2.5s retry attempt 3
+//	+? load user
+//
+// where "+?" marks layers without a time. A time annotates the nearest
+// layer with a message it wraps. If err is nil, Timeline returns an empty
+// string.
+func Timeline(err error) string {
+	type event struct {
+		msg  string
+		time time.Time
+	}
+	var events []event
+	var pending time.Time
+	for ; err != nil; err = Unwrap(err) {
+		var msg string
+		switch e := err.(type) {
+		case *withTime:
+			pending = e.time
+			continue
+		case layer:
+			msg = e.layerMessage()
+		case Liner:
+			msg = e.ErrorLineV(VerbosityNormal)
+		default:
+			msg = err.Error()
+		}
+		if msg != "" {
+			events = append(events, event{msg, pending})
+			pending = time.Time{}
+		}
+	}
+
+	var b strings.Builder
+	var prev time.Time
+	for i := len(events) - 1; i >= 0; i-- {
+		ev := events[i]
+		switch {
+		case ev.time.IsZero():
+			b.WriteString("+?")
+		case prev.IsZero():
+			b.WriteString(ev.time.Format(time.RFC3339Nano))
+		default:
+			b.WriteString("+" + ev.time.Sub(prev).String())
+		}
+		if !ev.time.IsZero() {
+			prev = ev.time
+		}
+		b.WriteByte(' ')
+		b.WriteString(ev.msg)
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
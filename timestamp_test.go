@@ -0,0 +1,53 @@
+package errors
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTime(t *testing.T) {
+	assert.Nil(t, WithTime(nil))
+	_, ok := Time(io.EOF)
+	assert.False(t, ok)
+
+	before := time.Now()
+	err := Wrap(WithTime(io.EOF), "read")
+	got, ok := Time(err)
+	assert.True(t, ok)
+	assert.False(t, got.Before(before))
+	assert.EqualError(t, err, "read: EOF")
+}
+
+func TestWithClock(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	api := NewErrorsApi(WithClock(func() time.Time { return now }))
+
+	err := api.WithTime(io.EOF)
+	now = now.Add(time.Hour)
+	err = api.WithTime(err)
+	got, ok := Time(err)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2020, 1, 2, 4, 4, 5, 0, time.UTC), got)
+}
+
+func TestTimeline(t *testing.T) {
+	assert.Equal(t, "", Timeline(nil))
+	assert.Equal(t, "+? EOF", Timeline(io.EOF))
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	api := NewErrorsApi(WithClock(func() time.Time { return now }))
+	err := api.WithTime(New("connection refused"))
+	now = now.Add(1200 * time.Millisecond)
+	err = api.WithTime(WithCode(Wrap(err, "retry attempt 2"), CodeUnavailable))
+	now = now.Add(2500 * time.Millisecond)
+	err = api.WithTime(Wrap(err, "retry attempt 3"))
+	err = WithMessage(err, "load user")
+
+	assert.Equal(t, "2024-05-01T10:00:00Z connection refused\n"+
+		"+1.2s retry attempt 2\n"+
+		"+2.5s retry attempt 3\n"+
+		"+? load user", Timeline(err))
+}
@@ -0,0 +1,143 @@
+package errors
+
+import (
+	"context"
+	"runtime/pprof"
+	"sort"
+	"strings"
+)
+
+// TraceIDKey and SpanIDKey are the detail keys WrapCtx stores the trace
+// and span IDs found in its context under.
+const (
+	TraceIDKey = "errors.trace_id"
+	SpanIDKey  = "errors.span_id"
+)
+
+// TraceExtractor returns the IDs of the trace and span ctx belongs to, and
+// whether there are any. Applications using a tracing library install one
+// that reads its span context with WithTraceExtractor.
+type TraceExtractor func(ctx context.Context) (traceID, spanID string, ok bool)
+
+// WithTraceExtractor sets the extractor WrapCtx reads trace and span IDs
+// with. It defaults to TraceparentFromContext.
+func WithTraceExtractor(extract TraceExtractor) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.TraceExtractor = extract
+	})
+}
+
+// PprofLabelPrefix is prepended to the keys of the pprof labels attached
+// as details by apis created with WithPprofLabels.
+const PprofLabelPrefix = "pprof."
+
+// WithPprofLabels makes WrapCtx and WrapfCtx attach the pprof labels of
+// their context, as set by pprof.Do for profiling, as details keyed by the
+// label key prefixed with PprofLabelPrefix, so errors inherit the request
+// and operation labels the application already maintains. Go does not let
+// the labels of a goroutine be read without its context, so errors created
+// without one do not get them.
+func WithPprofLabels(capture bool) ApiOption {
+	return apiOptionFunc(func(c *ApiConfig) {
+		c.PprofLabels = capture
+	})
+}
+
+type traceparentKey struct{}
+
+// ContextWithTraceparent returns a copy of ctx carrying traceparent, a W3C
+// Trace Context traceparent header such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", for
+// TraceparentFromContext to find.
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	return context.WithValue(ctx, traceparentKey{}, traceparent)
+}
+
+// TraceparentFromContext is a TraceExtractor that parses the traceparent
+// stored in ctx by ContextWithTraceparent. It reports false if there is
+// none, or if it is malformed.
+func TraceparentFromContext(ctx context.Context) (traceID, spanID string, ok bool) {
+	tp, _ := ctx.Value(traceparentKey{}).(string)
+	parts := strings.Split(tp, "-")
+	if len(parts) < 4 || !isHexID(parts[0], 2) || parts[0] == "ff" || !isHexID(parts[1], 32) || !isHexID(parts[2], 16) || !isHexID(parts[3], 2) {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// isHexID reports whether s is n lowercase hexadecimal digits. Trace and
+// span IDs, which are longer than the version and flags fields, must not be
+// all zeros.
+func isHexID(s string, n int) bool {
+	if len(s) != n || (n > 2 && strings.Trim(s, "0") == "") {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// WrapCtx is like Wrap, but also annotates err with the IDs of the trace
+// and span found in ctx, as the details TraceIDKey and SpanIDKey, so that
+// logged errors can be joined with their traces. Apis created with
+// WithPprofLabels attach the pprof labels of ctx as well.
+func WrapCtx(ctx context.Context, err error, message string) error {
+	return globalErrorsApi().WrapCtx(ctx, err, message)
+}
+
+// WrapfCtx is like WrapCtx, but formats its message as Wrapf does.
+func WrapfCtx(ctx context.Context, err error, format string, args ...interface{}) error {
+	return globalErrorsApi().WrapfCtx(ctx, err, format, args...)
+}
+
+func (e *errorsApi) WrapCtx(ctx context.Context, err error, message string) error {
+	return e.traced(ctx, e.wrap(1, err, message))
+}
+
+func (e *errorsApi) WrapfCtx(ctx context.Context, err error, format string, args ...interface{}) error {
+	if err == nil && !e.cfg.WrapNilAsNew {
+		return nil
+	}
+	return e.traced(ctx, e.wrapf(1, err, format, args))
+}
+
+// traced annotates err with the trace and span IDs of ctx, if any, and with
+// its pprof labels if e captures them.
+func (e *errorsApi) traced(ctx context.Context, err error) error {
+	if err == nil || ctx == nil {
+		return err
+	}
+	var kv []interface{}
+	extract := e.cfg.TraceExtractor
+	if extract == nil {
+		extract = TraceparentFromContext
+	}
+	if traceID, spanID, ok := extract(ctx); ok {
+		kv = append(kv, TraceIDKey, traceID, SpanIDKey, spanID)
+	}
+	if e.cfg.PprofLabels {
+		kv = appendPprofLabels(kv, ctx)
+	}
+	if len(kv) == 0 {
+		return err
+	}
+	return e.WithDetails(err, kv...)
+}
+
+// appendPprofLabels appends the pprof labels of ctx to kv as key-value
+// pairs, sorted by key.
+func appendPprofLabels(kv []interface{}, ctx context.Context) []interface{} {
+	var labels [][2]string
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		labels = append(labels, [2]string{key, value})
+		return true
+	})
+	sort.Slice(labels, func(i, j int) bool { return labels[i][0] < labels[j][0] })
+	for _, l := range labels {
+		kv = append(kv, PprofLabelPrefix+l[0], l[1])
+	}
+	return kv
+}
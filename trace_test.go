@@ -0,0 +1,82 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceparentFromContext(t *testing.T) {
+	tests := []struct {
+		traceparent     string
+		traceID, spanID string
+		ok              bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true},
+		{"", "", "", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "", "", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", "", "", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", "", "", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", "", "", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", "", "", false},
+	}
+	for _, tt := range tests {
+		traceID, spanID, ok := TraceparentFromContext(ContextWithTraceparent(context.Background(), tt.traceparent))
+		assert.Equal(t, tt.ok, ok, tt.traceparent)
+		assert.Equal(t, tt.traceID, traceID, tt.traceparent)
+		assert.Equal(t, tt.spanID, spanID, tt.traceparent)
+	}
+	_, _, ok := TraceparentFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestWrapCtx(t *testing.T) {
+	ctx := ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Nil(t, WrapCtx(ctx, nil, "read"))
+	assert.Nil(t, WrapfCtx(ctx, nil, "read %d", 1))
+
+	err := WrapCtx(ctx, io.EOF, "read")
+	assert.EqualError(t, err, "read: EOF")
+	assert.Equal(t, map[string]interface{}{
+		TraceIDKey: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanIDKey:  "00f067aa0ba902b7",
+	}, Details(err))
+	assert.Regexp(t, "^EOF\nread\ngithub.com/pkg/errors.TestWrapCtx\t.+/github.com/pkg/errors/trace_test.go:\\d+$", fmt.Sprintf("%+v", err))
+
+	err = WrapfCtx(context.Background(), io.EOF, "read %d", 1)
+	assert.EqualError(t, err, "read 1: EOF")
+	assert.Nil(t, Details(err))
+
+	api := NewErrorsApi(WithTraceExtractor(func(context.Context) (string, string, bool) {
+		return "trace", "span", true
+	}))
+	err = api.WrapCtx(context.Background(), io.EOF, "read")
+	assert.Equal(t, map[string]interface{}{TraceIDKey: "trace", SpanIDKey: "span"}, Details(err))
+}
+
+func TestWithPprofLabels(t *testing.T) {
+	api := NewErrorsApi(WithPprofLabels(true))
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("route", "/users", "op", "load"))
+
+	err := api.WrapCtx(ctx, io.EOF, "read")
+	assert.Equal(t, map[string]interface{}{
+		PprofLabelPrefix + "route": "/users",
+		PprofLabelPrefix + "op":    "load",
+	}, Details(err))
+
+	pprof.Do(context.Background(), pprof.Labels("op", "save"), func(ctx context.Context) {
+		err = api.WrapfCtx(ContextWithTraceparent(ctx, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"), io.EOF, "write %d", 1)
+	})
+	assert.Equal(t, map[string]interface{}{
+		TraceIDKey:              "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanIDKey:               "00f067aa0ba902b7",
+		PprofLabelPrefix + "op": "save",
+	}, Details(err))
+
+	assert.Nil(t, Details(WrapCtx(ctx, io.EOF, "read")))
+}
@@ -0,0 +1,83 @@
+package errors
+
+// Node is a layer of the tree of errors returned by Tree.
+type Node struct {
+	// Err is the error of this layer, together with its causes.
+	Err error
+	// Message is the message of this layer alone. It is empty for layers
+	// that only annotate their cause, and for errors that join several.
+	Message string
+	// Stack is the stack trace captured by this layer, if any.
+	Stack StackTrace
+	// Details are the key-value pairs attached by this layer, if any.
+	Details map[string]interface{}
+	// Children are the errors this layer wraps: none for the root of a
+	// chain, one for a wrapper and one per error for a joined error.
+	Children []*Node
+}
+
+// Tree returns the structure of err as a tree of nodes, one per layer,
+// following Unwrap, Cause and Unwrap() []error as Walk does. It is meant
+// for tooling that analyzes how a failure came about, without parsing the
+// formatted error. If err is nil, Tree returns nil.
+func Tree(err error) *Node {
+	if err == nil {
+		return nil
+	}
+	root := newNode(err)
+	for n := root; ; {
+		if multi, ok := n.Err.(interface{ Unwrap() []error }); ok {
+			for _, child := range multi.Unwrap() {
+				if child != nil {
+					n.Children = append(n.Children, Tree(child))
+				}
+			}
+			return root
+		}
+		next := unwrapOnce(n.Err)
+		if next == nil {
+			return root
+		}
+		child := newNode(next)
+		n.Children = []*Node{child}
+		n = child
+	}
+}
+
+// ownMessage returns the message of the outermost layer of err alone, as
+// reported in Node.Message.
+func ownMessage(err error) string {
+	switch e := err.(type) {
+	case layer:
+		return e.layerMessage()
+	case interface{ Unwrap() []error }:
+		return ""
+	case Liner:
+		return e.ErrorLineV(VerbosityNormal)
+	}
+	return err.Error()
+}
+
+// newNode returns the node of the outermost layer of err, without children.
+func newNode(err error) *Node {
+	n := &Node{Err: err, Message: ownMessage(err)}
+	switch e := err.(type) {
+	case layer:
+		if st := e.layerStack(); st != nil && len(*st) > 0 {
+			n.Stack = st.StackTrace()
+		}
+		if d, ok := err.(*withDetails); ok {
+			n.Details = make(map[string]interface{}, len(d.details))
+			for _, kv := range d.details {
+				n.Details[kv.key] = kv.get()
+			}
+		}
+		return n
+	case interface{ Unwrap() []error }:
+		return n
+	}
+	if st, ok := err.(interface{ StackTrace() StackTrace }); ok {
+		n.Stack = st.StackTrace()
+	}
+	return n
+}
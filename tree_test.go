@@ -0,0 +1,52 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree(t *testing.T) {
+	assert.Nil(t, Tree(nil))
+
+	inner := New("inner")
+	detailed := WithDetails(inner, "id", 7)
+	wrapped := Wrap(detailed, "wrapped")
+	std := fmt.Errorf("std: %w", io.EOF)
+	joined := Join(wrapped, std)
+	err := WithMessage(joined, "top")
+
+	root := Tree(err)
+	assert.Same(t, err, root.Err)
+	assert.Equal(t, "top", root.Message)
+	assert.Nil(t, root.Stack)
+	assert.Len(t, root.Children, 1)
+
+	j := root.Children[0]
+	assert.Same(t, joined, j.Err)
+	assert.Equal(t, "", j.Message)
+	assert.Len(t, j.Children, 2)
+
+	w := j.Children[0]
+	assert.Equal(t, "wrapped", w.Message)
+	assert.Equal(t, wrapped.(*withStack).StackTrace(), w.Stack)
+
+	d := w.Children[0]
+	assert.Same(t, detailed, d.Err)
+	assert.Equal(t, "", d.Message)
+	assert.Nil(t, d.Stack)
+	assert.Equal(t, map[string]interface{}{"id": 7}, d.Details)
+
+	n := d.Children[0]
+	assert.Equal(t, "inner", n.Message)
+	assert.Equal(t, inner.(*fundamental).StackTrace(), n.Stack)
+	assert.Empty(t, n.Children)
+
+	s := j.Children[1]
+	assert.Equal(t, "std: EOF", s.Message)
+	assert.Len(t, s.Children, 1)
+	assert.Equal(t, "EOF", s.Children[0].Message)
+	assert.Empty(t, s.Children[0].Children)
+}
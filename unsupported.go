@@ -0,0 +1,13 @@
+//go:build go1.21
+// +build go1.21
+
+package errors
+
+import stderrors "errors"
+
+// ErrUnsupported indicates that a requested operation cannot be performed,
+// because it is unsupported. It is the ErrUnsupported of the standard
+// library, so that Is matches the errors of the packages that return it,
+// and is re-exported here so that this package can replace the standard
+// library one wherever it is imported.
+var ErrUnsupported = stderrors.ErrUnsupported
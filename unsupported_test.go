@@ -0,0 +1,16 @@
+//go:build go1.21
+// +build go1.21
+
+package errors
+
+import (
+	stderrors "errors"
+	"testing"
+)
+
+func TestErrUnsupported(t *testing.T) {
+	err := Wrap(stderrors.ErrUnsupported, "link")
+	if !Is(err, ErrUnsupported) || !stderrors.Is(err, ErrUnsupported) {
+		t.Errorf("Is(%v, ErrUnsupported) = false, want true", err)
+	}
+}
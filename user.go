@@ -0,0 +1,72 @@
+package errors
+
+// WithUserMessage annotates err with a message meant for end users, as
+// opposed to the developer-oriented messages of the chain.
+// If err is nil, WithUserMessage returns nil.
+func WithUserMessage(err error, message string) error {
+	return globalErrorsApi().WithUserMessage(err, message)
+}
+
+// UserMessage returns the user message of the outermost error in err's
+// chain that has one, or an empty string if there is none.
+func UserMessage(err error) string {
+	for ; err != nil; err = unwrapOnce(err) {
+		if u, ok := err.(*withUserMessage); ok {
+			return u.msg
+		}
+	}
+	return ""
+}
+
+type withUserMessage struct {
+	annotation
+	msg string
+}
+
+func (w *withUserMessage) rewrap(cause error) error {
+	c := *w
+	c.cause = cause
+	return &c
+}
+
+// WithHint annotates err with a hint telling users how to resolve it.
+// If err is nil, WithHint returns nil.
+func WithHint(err error, hint string) error {
+	return globalErrorsApi().WithHint(err, hint)
+}
+
+// Hints returns the hints attached to err's chain, outermost first.
+func Hints(err error) []string {
+	var hints []string
+	for ; err != nil; err = unwrapOnce(err) {
+		if h, ok := err.(*withHint); ok {
+			hints = append(hints, h.hint)
+		}
+	}
+	return hints
+}
+
+type withHint struct {
+	annotation
+	hint string
+}
+
+func (w *withHint) rewrap(cause error) error {
+	c := *w
+	c.cause = cause
+	return &c
+}
+
+func (e *errorsApi) WithUserMessage(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &withUserMessage{annotation{err}, message}
+}
+
+func (e *errorsApi) WithHint(err error, hint string) error {
+	if err == nil {
+		return nil
+	}
+	return &withHint{annotation{err}, hint}
+}
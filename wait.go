@@ -0,0 +1,43 @@
+package errors
+
+import "sync"
+
+// WaitAll calls every function in fns in its own goroutine and waits for
+// all of them to return. It returns nil if they all succeed; otherwise it
+// returns the Join of their errors, in the order of fns.
+//
+// An error without a stack trace is annotated with the stack of the call
+// to WaitAll that started its goroutine, which tells more than that of the
+// goroutine itself. A panic is recovered and converted as by WrapPanic, so
+// it fails its function instead of crashing the program.
+func WaitAll(fns ...func() error) error {
+	return globalErrorsApi().WaitAll(fns...)
+}
+
+func (e *errorsApi) WaitAll(fns ...func() error) error {
+	st := e.callers(0)
+	errs := make([]error, len(fns))
+	var wg sync.WaitGroup
+	wg.Add(len(fns))
+	for i, fn := range fns {
+		go func(i int, fn func() error) {
+			defer wg.Done()
+			errs[i] = e.waitOne(fn, st)
+		}(i, fn)
+	}
+	wg.Wait()
+	return e.Join(errs...)
+}
+
+// waitOne calls fn on behalf of WaitAll, whose stack is st.
+func (e *errorsApi) waitOne(fn func() error, st *stack) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = e.panicError(r)
+		}
+	}()
+	if err = fn(); err != nil && !hasStack(err) {
+		err = e.withStack(err, "", nil, st)
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitAll(t *testing.T) {
+	assert.NoError(t, WaitAll())
+	assert.NoError(t, WaitAll(func() error { return nil }, func() error { return nil }))
+
+	withStack := New("with stack")
+	err := WaitAll(
+		func() error { return io.EOF },
+		func() error { return nil },
+		func() error { panic("boom") },
+		func() error { return withStack },
+	)
+	assert.EqualError(t, err, "EOF\npanic: boom\nwith stack")
+
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	assert.Len(t, errs, 3)
+	assert.True(t, Is(errs[0], io.EOF))
+	assert.Regexp(t, "^EOF\ngithub.com/pkg/errors.TestWaitAll\t.+/github.com/pkg/errors/wait_test.go:17$", fmt.Sprintf("%+v", errs[0]))
+	assert.Regexp(t, "^panic: boom\ngithub.com/pkg/errors.TestWaitAll.func5\t.+/github.com/pkg/errors/wait_test.go:20$", fmt.Sprintf("%+v", errs[1]))
+	assert.Same(t, withStack, errs[2])
+}
+
+func TestWaitAllStacks(t *testing.T) {
+	err := WaitAll(func() error { return io.EOF }, func() error { return io.ErrUnexpectedEOF })
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	for _, err := range errs {
+		st := nearestStack(err)
+		if assert.NotEmpty(t, st) {
+			assert.Equal(t, "github.com/pkg/errors.TestWaitAllStacks", st[0].name())
+			assert.Equal(t, "wait_test.go", path.Base(st[0].file()))
+		}
+	}
+}